@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Bookmark is a named bucket and prefix
+type Bookmark struct {
+	Name   string `json:"name"`
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+}
+
+// URL returns the S3 URL the bookmark points to
+func (b Bookmark) URL() string {
+	return fmt.Sprintf("s3://%s/%s", b.Bucket, b.Prefix)
+}
+
+// getBookmarksPath returns the path to the bookmarks file, next to the
+// state file
+func getBookmarksPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ls3_bookmarks.json"), nil
+}
+
+// loadBookmarks loads the saved bookmarks. A missing file means there are
+// none yet.
+func loadBookmarks() ([]Bookmark, error) {
+	path, err := getBookmarksPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var bookmarks []Bookmark
+	err = json.Unmarshal(data, &bookmarks)
+	return bookmarks, err
+}
+
+// saveBookmarks writes the bookmarks file, replacing it atomically
+func saveBookmarks(bookmarks []Bookmark) error {
+	path, err := getBookmarksPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(bookmarks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// findBookmark returns the index of the bookmark called name, preferring an
+// exact match over one differing only in case, or -1 if there is none
+func findBookmark(bookmarks []Bookmark, name string) int {
+	match := -1
+	for i, b := range bookmarks {
+		if b.Name == name {
+			return i
+		}
+		if match < 0 && strings.EqualFold(b.Name, name) {
+			match = i
+		}
+	}
+	return match
+}
+
+// setBookmark adds b, or moves the bookmark with the same name to b's
+// location. It reports whether an existing bookmark was replaced.
+func setBookmark(bookmarks []Bookmark, b Bookmark) ([]Bookmark, bool) {
+	for i := range bookmarks {
+		if bookmarks[i].Name == b.Name {
+			bookmarks[i] = b
+			return bookmarks, true
+		}
+	}
+	return append(bookmarks, b), false
+}
+
+// renameBookmark renames the bookmark at index i, refusing names that are
+// empty or taken by another bookmark
+func renameBookmark(bookmarks []Bookmark, i int, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("the name cannot be empty")
+	}
+	for j, b := range bookmarks {
+		if j != i && b.Name == name {
+			return fmt.Errorf("a bookmark called %q already exists", name)
+		}
+	}
+	bookmarks[i].Name = name
+	return nil
+}
+
+// defaultBookmarkName suggests a name for a bookmark of bucket and prefix:
+// the innermost folder, or the bucket name at its root
+func defaultBookmarkName(bucket, prefix string) string {
+	if prefix == "" {
+		return bucket
+	}
+	return entryBaseName(prefix)
+}
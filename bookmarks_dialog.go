@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// showBookmarksPicker lists the bookmarks to jump to (Enter), rename ('r')
+// or delete ('x' or Delete). onChange is called with the bookmarks after
+// each rename or deletion so they can be saved; it returns an error to show
+// if saving failed. onJump and onClose dismiss the picker.
+func showBookmarksPicker(app *tview.Application, bookmarks []Bookmark, onJump func(b Bookmark), onChange func(bookmarks []Bookmark) error, onClose func()) tview.Primitive {
+	table := tview.NewTable().
+		SetBorders(false).
+		SetSelectable(true, false)
+	table.SetBorder(true)
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(table, 0, 1, true)
+
+	title := func(message string) {
+		if message == "" {
+			message = "Enter: go, 'r': rename, 'x': delete, ESC: close"
+		}
+		table.SetTitle(fmt.Sprintf(" Bookmarks (%s) ", message))
+	}
+
+	render := func() {
+		table.Clear()
+		table.SetCell(0, 0, tview.NewTableCell("Name").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		table.SetCell(0, 1, tview.NewTableCell("Location").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		if len(bookmarks) == 0 {
+			table.SetCell(1, 0, tview.NewTableCell("No bookmarks yet, press 'B' in a folder to add one").SetTextColor(tcell.ColorGray).SetSelectable(false))
+			return
+		}
+		for i, b := range bookmarks {
+			table.SetCell(i+1, 0, tview.NewTableCell(b.Name))
+			table.SetCell(i+1, 1, tview.NewTableCell(b.URL()))
+		}
+		if row, _ := table.GetSelection(); row > len(bookmarks) {
+			table.Select(len(bookmarks), 0)
+		}
+	}
+
+	save := func() {
+		if err := onChange(bookmarks); err != nil {
+			title(fmt.Sprintf("Failed to save: %v", err))
+		} else {
+			title("")
+		}
+		render()
+	}
+
+	selected := func() (int, bool) {
+		row, _ := table.GetSelection()
+		if row > 0 && row-1 < len(bookmarks) { // Skip header row
+			return row - 1, true
+		}
+		return 0, false
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		i, ok := selected()
+		switch {
+		case event.Key() == tcell.KeyEscape:
+			onClose()
+		case event.Key() == tcell.KeyEnter:
+			if ok {
+				onJump(bookmarks[i])
+			}
+		case event.Key() == tcell.KeyDelete || (event.Key() == tcell.KeyRune && event.Rune() == 'x'):
+			if ok {
+				bookmarks = append(bookmarks[:i], bookmarks[i+1:]...)
+				save()
+			}
+		case event.Key() == tcell.KeyRune && event.Rune() == 'r':
+			if !ok {
+				return nil
+			}
+			var input *tview.InputField
+			restore := func() {
+				layout.RemoveItem(input)
+				app.SetFocus(table)
+			}
+			input = newInputBox("Rename bookmark", "Name: ", bookmarks[i].Name, func(name string) {
+				restore()
+				if err := renameBookmark(bookmarks, i, name); err != nil {
+					title(err.Error())
+					return
+				}
+				save()
+			}, restore)
+			layout.AddItem(input, 3, 0, true)
+			app.SetFocus(input)
+		default:
+			return event
+		}
+		return nil
+	})
+
+	title("")
+	render()
+	table.Select(1, 0)
+
+	return centered(layout, 100, 20)
+}
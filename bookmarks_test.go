@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBookmarksRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	bookmarks, err := loadBookmarks()
+	if err != nil || len(bookmarks) != 0 {
+		t.Fatalf("expected no bookmarks without a file, got %v (%v)", bookmarks, err)
+	}
+
+	bookmarks = []Bookmark{
+		{Name: "logs", Bucket: "data", Prefix: "app/logs/"},
+		{Name: "root", Bucket: "archive"},
+	}
+	if err := saveBookmarks(bookmarks); err != nil {
+		t.Fatalf("saveBookmarks returned an error: %v", err)
+	}
+	loaded, err := loadBookmarks()
+	if err != nil {
+		t.Fatalf("loadBookmarks returned an error: %v", err)
+	}
+	if len(loaded) != 2 || loaded[0] != bookmarks[0] || loaded[1] != bookmarks[1] {
+		t.Errorf("expected %v, got %v", bookmarks, loaded)
+	}
+
+	path, _ := getBookmarksPath()
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be renamed into place, got %v", err)
+	}
+}
+
+func TestLoadBookmarksUnreadable(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, ".ls3_bookmarks.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatalf("failed to write bookmarks file: %v", err)
+	}
+
+	if _, err := loadBookmarks(); err == nil {
+		t.Error("expected an error for a bookmarks file that cannot be parsed")
+	}
+}
+
+func TestFindBookmark(t *testing.T) {
+	bookmarks := []Bookmark{{Name: "Logs"}, {Name: "logs"}, {Name: "Reports"}}
+
+	if i := findBookmark(bookmarks, "logs"); i != 1 {
+		t.Errorf("expected the exact match, got %d", i)
+	}
+	if i := findBookmark(bookmarks, "reports"); i != 2 {
+		t.Errorf("expected a case-insensitive match, got %d", i)
+	}
+	if i := findBookmark(bookmarks, "missing"); i != -1 {
+		t.Errorf("expected no match, got %d", i)
+	}
+}
+
+func TestSetAndRenameBookmark(t *testing.T) {
+	bookmarks, replaced := setBookmark(nil, Bookmark{Name: "logs", Bucket: "data", Prefix: "logs/"})
+	if replaced || len(bookmarks) != 1 {
+		t.Fatalf("expected a new bookmark, got %v", bookmarks)
+	}
+	bookmarks, _ = setBookmark(bookmarks, Bookmark{Name: "other", Bucket: "data"})
+	bookmarks, replaced = setBookmark(bookmarks, Bookmark{Name: "logs", Bucket: "data", Prefix: "app/logs/"})
+	if !replaced || len(bookmarks) != 2 || bookmarks[0].Prefix != "app/logs/" {
+		t.Errorf("expected the bookmark to move, got %v", bookmarks)
+	}
+
+	if err := renameBookmark(bookmarks, 0, "other"); err == nil {
+		t.Error("expected renaming onto an existing name to fail")
+	}
+	if err := renameBookmark(bookmarks, 0, "  "); err == nil {
+		t.Error("expected an empty name to fail")
+	}
+	if err := renameBookmark(bookmarks, 0, " app logs "); err != nil || bookmarks[0].Name != "app logs" {
+		t.Errorf("expected the bookmark to be renamed, got %q (%v)", bookmarks[0].Name, err)
+	}
+}
+
+func TestDefaultBookmarkName(t *testing.T) {
+	if name := defaultBookmarkName("data", "app/logs/"); name != "logs" {
+		t.Errorf("expected logs, got %s", name)
+	}
+	if name := defaultBookmarkName("data", ""); name != "data" {
+		t.Errorf("expected data, got %s", name)
+	}
+}
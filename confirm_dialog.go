@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+)
+
+// showConfirmDialog displays a modal asking the user to confirm an action.
+// onConfirm is called when the confirmLabel button is chosen, onCancel when
+// the dialog is dismissed in any other way.
+func showConfirmDialog(text, confirmLabel string, onConfirm, onCancel func()) *tview.Modal {
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{confirmLabel, "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == confirmLabel {
+				onConfirm()
+			} else {
+				onCancel()
+			}
+		})
+
+	// Default to the safe choice
+	modal.SetFocus(1)
+
+	return modal
+}
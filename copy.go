@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Objects larger than maxCopyObjectSize cannot be copied with a single
+// CopyObject request and are copied in parts of copyPartSize bytes instead
+var (
+	maxCopyObjectSize int64 = 5 * 1024 * 1024 * 1024
+	copyPartSize      int64 = 512 * 1024 * 1024
+)
+
+// yankedEntries are entries picked in one listing to be pasted into another
+type yankedEntries struct {
+	bucket  string
+	prefix  string
+	entries []ObjectEntry
+	move    bool
+}
+
+// copyTarget is an object and the key it will be copied to
+type copyTarget struct {
+	srcKey  string
+	destKey string
+	size    int64
+	etag    string
+}
+
+// copySource builds the URL encoded "bucket/key" source of a copy request
+func copySource(bucketName, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.QueryEscape(segment), "+", "%20")
+	}
+	return bucketName + "/" + strings.Join(segments, "/")
+}
+
+// planCopies maps the objects covered by entries in srcBucket to keys under
+// destPrefix in destBucket, keeping their layout relative to srcPrefix.
+// Directory entries are expanded to every object below them. Copying a
+// directory into itself or an object onto itself is refused.
+func planCopies(ctx context.Context, client S3Client, srcBucket, srcPrefix string, entries []ObjectEntry, destBucket, destPrefix string) ([]copyTarget, error) {
+	if srcBucket == destBucket {
+		for _, entry := range entries {
+			if entry.IsDirectory && strings.HasPrefix(destPrefix, entry.Key) {
+				return nil, fmt.Errorf("cannot copy %s into itself", entry.Key)
+			}
+		}
+	}
+
+	objects, err := collectEntryObjects(ctx, client, srcBucket, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []copyTarget
+	for _, object := range objects {
+		destKey := destPrefix + strings.TrimPrefix(*object.Key, srcPrefix)
+		if srcBucket == destBucket && destKey == *object.Key {
+			return nil, fmt.Errorf("cannot copy %s onto itself", *object.Key)
+		}
+
+		target := copyTarget{srcKey: *object.Key, destKey: destKey}
+		if object.Size != nil {
+			target.size = *object.Size
+		}
+		if object.ETag != nil {
+			target.etag = *object.ETag
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// copyS3Object copies an object server-side. destClient must be a client for
+// the destination bucket's region and srcClient one for the source bucket's.
+// Objects up to maxCopyObjectSize are copied with a single CopyObject request,
+// larger ones part by part. The copy keeps the source's storage class and
+// encryption, which S3 would otherwise reset to the destination's defaults.
+// It is conditional on the source ETag so an object overwritten in the
+// meantime fails instead of being copied half old, half new.
+func copyS3Object(ctx context.Context, srcClient, destClient S3Client, srcBucket, destBucket string, target copyTarget, onProgress func(current, total int64)) error {
+	if onProgress == nil {
+		onProgress = func(current, total int64) {}
+	}
+	onProgress(0, target.size)
+
+	head, err := srcClient.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &srcBucket,
+		Key:    &target.srcKey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", target.srcKey, err)
+	}
+
+	if target.size > maxCopyObjectSize {
+		return copyS3ObjectMultipart(ctx, srcClient, destClient, srcBucket, destBucket, target, head, onProgress)
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:               &destBucket,
+		Key:                  &target.destKey,
+		CopySource:           aws.String(copySource(srcBucket, target.srcKey)),
+		MetadataDirective:    types.MetadataDirectiveCopy,
+		TaggingDirective:     types.TaggingDirectiveCopy,
+		StorageClass:         head.StorageClass,
+		ServerSideEncryption: head.ServerSideEncryption,
+		SSEKMSKeyId:          head.SSEKMSKeyId,
+		BucketKeyEnabled:     head.BucketKeyEnabled,
+	}
+	if target.etag != "" {
+		input.CopySourceIfMatch = aws.String(target.etag)
+	}
+	if _, err := destClient.CopyObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", target.srcKey, err)
+	}
+
+	onProgress(target.size, target.size)
+	return nil
+}
+
+// copyS3ObjectMultipart copies a large object with UploadPartCopy requests.
+// Unlike CopyObject this does not carry over the object's headers, user
+// metadata and tags, so they are taken from head, the source's HeadObject
+// response, and from its tags, and set on the new upload.
+func copyS3ObjectMultipart(ctx context.Context, srcClient, destClient S3Client, srcBucket, destBucket string, target copyTarget, head *s3.HeadObjectOutput, onProgress func(current, total int64)) error {
+	tagging, err := srcClient.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: &srcBucket,
+		Key:    &target.srcKey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read the tags of %s: %w", target.srcKey, err)
+	}
+	tags := url.Values{}
+	for _, tag := range tagging.TagSet {
+		tags.Set(aws.ToString(tag.Key), aws.ToString(tag.Value))
+	}
+
+	// Grow the part size if the object would otherwise need too many parts
+	partSize := copyPartSize
+	if target.size/partSize >= maxUploadParts {
+		partSize = target.size/(maxUploadParts-1) + 1
+	}
+
+	created, err := destClient.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               &destBucket,
+		Key:                  &target.destKey,
+		ContentType:          head.ContentType,
+		ContentEncoding:      head.ContentEncoding,
+		ContentDisposition:   head.ContentDisposition,
+		ContentLanguage:      head.ContentLanguage,
+		CacheControl:         head.CacheControl,
+		Metadata:             head.Metadata,
+		Tagging:              aws.String(tags.Encode()),
+		StorageClass:         head.StorageClass,
+		ServerSideEncryption: head.ServerSideEncryption,
+		SSEKMSKeyId:          head.SSEKMSKeyId,
+		BucketKeyEnabled:     head.BucketKeyEnabled,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart copy of %s: %w", target.srcKey, err)
+	}
+
+	abort := func(cause error) error {
+		// Use a fresh context so the abort still goes out after cancellation
+		destClient.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   &destBucket,
+			Key:      &target.destKey,
+			UploadId: created.UploadId,
+		})
+		return cause
+	}
+
+	var parts []types.CompletedPart
+	for offset, partNumber := int64(0), int32(1); offset < target.size; offset, partNumber = offset+partSize, partNumber+1 {
+		end := offset + partSize - 1
+		if end >= target.size {
+			end = target.size - 1
+		}
+
+		input := &s3.UploadPartCopyInput{
+			Bucket:          &destBucket,
+			Key:             &target.destKey,
+			UploadId:        created.UploadId,
+			PartNumber:      aws.Int32(partNumber),
+			CopySource:      aws.String(copySource(srcBucket, target.srcKey)),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+		}
+		if target.etag != "" {
+			input.CopySourceIfMatch = aws.String(target.etag)
+		}
+		result, err := destClient.UploadPartCopy(ctx, input)
+		if err != nil {
+			return abort(fmt.Errorf("failed to copy part %d of %s: %w", partNumber, target.srcKey, err))
+		}
+
+		var etag *string
+		if result.CopyPartResult != nil {
+			etag = result.CopyPartResult.ETag
+		}
+		parts = append(parts, types.CompletedPart{
+			ETag:       etag,
+			PartNumber: aws.Int32(partNumber),
+		})
+		onProgress(end+1, target.size)
+	}
+
+	_, err = destClient.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &destBucket,
+		Key:             &target.destKey,
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("failed to complete multipart copy of %s: %w", target.srcKey, err))
+	}
+
+	return nil
+}
+
+// transferS3Object copies target from srcBucket to destBucket using clients
+// for each bucket's region, and deletes the source afterwards if move is set
+func transferS3Object(ctx context.Context, clientManager *ClientManager, srcBucket, destBucket string, target copyTarget, move bool, onProgress func(current, total int64)) error {
+	srcClient, err := clientManager.GetClientForBucket(ctx, srcBucket)
+	if err != nil {
+		return err
+	}
+	destClient, err := clientManager.GetClientForBucket(ctx, destBucket)
+	if err != nil {
+		return err
+	}
+
+	if err := copyS3Object(ctx, srcClient, destClient, srcBucket, destBucket, target, onProgress); err != nil {
+		return err
+	}
+	if !move {
+		return nil
+	}
+
+	if _, err := deleteS3Objects(ctx, srcClient, srcBucket, []types.Object{{Key: aws.String(target.srcKey)}}, nil); err != nil {
+		return fmt.Errorf("copied, but failed to delete the source: %w", err)
+	}
+	return nil
+}
+
+// copyWorkers is the number of objects copied concurrently by a rename
+const copyWorkers = 8
+
+// planRename plans renaming entry, a file or a directory, to newName within
+// the same parent prefix. For a directory every key under it is renamed.
+// Renaming onto an existing object or non-empty prefix is refused.
+func planRename(ctx context.Context, client S3Client, bucketName string, entry ObjectEntry, newName string) ([]copyTarget, error) {
+	if err := validateEntryName(newName); err != nil {
+		return nil, err
+	}
+
+	newKey := parentPrefix(entry.Key) + newName
+	if entry.IsDirectory {
+		newKey += "/"
+	}
+	if newKey == entry.Key {
+		return nil, fmt.Errorf("%s already has that name", entry.Key)
+	}
+
+	// Keys sort lexically, so if newKey itself exists it is listed first
+	existing, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  &bucketName,
+		Prefix:  &newKey,
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing.Contents) > 0 && (entry.IsDirectory || *existing.Contents[0].Key == newKey) {
+		return nil, fmt.Errorf("%s already exists", newKey)
+	}
+
+	return planCopies(ctx, client, bucketName, entry.Key, []ObjectEntry{entry}, bucketName, newKey)
+}
+
+// renameS3Objects copies every target within bucketName and then deletes
+// the originals. If any copy fails nothing is deleted, so the originals are
+// never lost; the copies made so far are left in place. onProgress receives
+// the number of objects and bytes copied.
+func renameS3Objects(ctx context.Context, client S3Client, bucketName string, targets []copyTarget, onProgress func(objectsDone, objectsTotal int, current, total int64)) error {
+	var totalBytes int64
+	for _, target := range targets {
+		totalBytes += target.size
+	}
+
+	var bytesDone int64
+	var objectsDone int32
+	report := func() {
+		if onProgress != nil {
+			onProgress(int(atomic.LoadInt32(&objectsDone)), len(targets), atomic.LoadInt64(&bytesDone), totalBytes)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	jobs := make(chan copyTarget)
+	var wg sync.WaitGroup
+	for i := 0; i < copyWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				var last int64
+				err := copyS3Object(ctx, client, client, bucketName, bucketName, target, func(current, total int64) {
+					atomic.AddInt64(&bytesDone, current-last)
+					last = current
+				})
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					cancel()
+					continue
+				}
+				atomic.AddInt32(&objectsDone, 1)
+				report()
+			}
+		}()
+	}
+
+dispatch:
+	for _, target := range targets {
+		select {
+		case jobs <- target:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	sources := make([]types.Object, len(targets))
+	for i, target := range targets {
+		sources[i] = types.Object{Key: aws.String(target.srcKey)}
+	}
+	if _, err := deleteS3Objects(ctx, client, bucketName, sources, nil); err != nil {
+		return fmt.Errorf("copied, but failed to delete the originals: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestCopySource(t *testing.T) {
+	tests := map[string]string{
+		"file.txt":              "bucket/file.txt",
+		"dir/my file+1.txt":     "bucket/dir/my%20file%2B1.txt",
+		"dir/sub/ü&?.txt":       "bucket/dir/sub/%C3%BC%26%3F.txt",
+		"dir/trailing/":         "bucket/dir/trailing/",
+		"percent%20literal.txt": "bucket/percent%2520literal.txt",
+	}
+	for key, expected := range tests {
+		if actual := copySource("bucket", key); actual != expected {
+			t.Errorf("copySource(%q): expected %q, got %q", key, expected, actual)
+		}
+	}
+}
+
+func TestPlanCopies(t *testing.T) {
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("src/dir/a.txt"), Size: aws.Int64(1), ETag: aws.String(`"a"`)},
+					{Key: aws.String("src/dir/sub/b.txt"), Size: aws.Int64(2)},
+				},
+			}, nil
+		},
+	}
+
+	entries := []ObjectEntry{
+		{Key: "src/dir/", IsDirectory: true},
+		{Key: "src/c.txt", Size: 3},
+	}
+	targets, err := planCopies(context.TODO(), mockClient, "bucket", "src/", entries, "bucket", "dest/")
+	if err != nil {
+		t.Fatalf("planCopies returned an error: %v", err)
+	}
+
+	expected := []copyTarget{
+		{srcKey: "src/dir/a.txt", destKey: "dest/dir/a.txt", size: 1, etag: `"a"`},
+		{srcKey: "src/dir/sub/b.txt", destKey: "dest/dir/sub/b.txt", size: 2},
+		{srcKey: "src/c.txt", destKey: "dest/c.txt", size: 3},
+	}
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %d targets, got %d", len(expected), len(targets))
+	}
+	for i := range expected {
+		if targets[i] != expected[i] {
+			t.Errorf("target %d: expected %+v, got %+v", i, expected[i], targets[i])
+		}
+	}
+
+	// A directory cannot be pasted into itself, nor a file onto itself
+	if _, err := planCopies(context.TODO(), mockClient, "bucket", "src/", entries, "bucket", "src/dir/sub/"); err == nil {
+		t.Error("expected copying a directory into itself to fail")
+	}
+	if _, err := planCopies(context.TODO(), mockClient, "bucket", "src/", entries[1:], "bucket", "src/"); err == nil {
+		t.Error("expected copying a file onto itself to fail")
+	}
+	if _, err := planCopies(context.TODO(), mockClient, "bucket", "src/", entries[1:], "other-bucket", "src/"); err != nil {
+		t.Errorf("expected copying to the same key in another bucket to work, got %v", err)
+	}
+}
+
+func TestCopyS3Object(t *testing.T) {
+	var input *s3.CopyObjectInput
+	mockClient := &mockS3Client{
+		HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{
+				StorageClass:         types.StorageClassStandardIa,
+				ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+				SSEKMSKeyId:          aws.String("key-1"),
+			}, nil
+		},
+		CopyObjectFunc: func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+			input = params
+			return &s3.CopyObjectOutput{}, nil
+		},
+	}
+
+	target := copyTarget{srcKey: "a/file.txt", destKey: "b/file.txt", size: 5, etag: `"abc"`}
+	var lastProgress int64
+	err := copyS3Object(context.TODO(), mockClient, mockClient, "src-bucket", "dest-bucket", target, func(current, total int64) {
+		lastProgress = current
+	})
+	if err != nil {
+		t.Fatalf("copyS3Object returned an error: %v", err)
+	}
+
+	if *input.Bucket != "dest-bucket" || *input.Key != "b/file.txt" {
+		t.Errorf("expected copy to dest-bucket/b/file.txt, got %s/%s", *input.Bucket, *input.Key)
+	}
+	if *input.CopySource != "src-bucket/a/file.txt" {
+		t.Errorf("unexpected copy source %s", *input.CopySource)
+	}
+	if input.CopySourceIfMatch == nil || *input.CopySourceIfMatch != `"abc"` {
+		t.Error("expected the copy to be conditional on the source ETag")
+	}
+	if input.StorageClass != types.StorageClassStandardIa {
+		t.Errorf("expected the storage class to be kept, got %q", input.StorageClass)
+	}
+	if input.ServerSideEncryption != types.ServerSideEncryptionAwsKms || aws.ToString(input.SSEKMSKeyId) != "key-1" {
+		t.Errorf("expected the KMS encryption to be kept, got %q with key %q", input.ServerSideEncryption, aws.ToString(input.SSEKMSKeyId))
+	}
+	if lastProgress != 5 {
+		t.Errorf("expected progress to reach 5, got %d", lastProgress)
+	}
+}
+
+func TestCopyS3ObjectMultipart(t *testing.T) {
+	oldMax, oldPartSize := maxCopyObjectSize, copyPartSize
+	maxCopyObjectSize, copyPartSize = 8, 4
+	t.Cleanup(func() {
+		maxCopyObjectSize, copyPartSize = oldMax, oldPartSize
+	})
+
+	var ranges []string
+	var created *s3.CreateMultipartUploadInput
+	var completed *s3.CompleteMultipartUploadInput
+	mockClient := &mockS3Client{
+		HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{
+				ContentType:          aws.String("text/plain"),
+				Metadata:             map[string]string{"owner": "data-team"},
+				StorageClass:         types.StorageClassIntelligentTiering,
+				ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+				SSEKMSKeyId:          aws.String("key-1"),
+			}, nil
+		},
+		GetObjectTaggingFunc: func(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+			return &s3.GetObjectTaggingOutput{
+				TagSet: []types.Tag{{Key: aws.String("project"), Value: aws.String("a b")}},
+			}, nil
+		},
+		CreateMultipartUploadFunc: func(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			created = params
+			return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+		},
+		UploadPartCopyFunc: func(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+			ranges = append(ranges, *params.CopySourceRange)
+			return &s3.UploadPartCopyOutput{CopyPartResult: &types.CopyPartResult{ETag: aws.String("etag")}}, nil
+		},
+		CompleteMultipartUploadFunc: func(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+			completed = params
+			return &s3.CompleteMultipartUploadOutput{}, nil
+		},
+	}
+
+	target := copyTarget{srcKey: "big.bin", destKey: "copy.bin", size: 10}
+	if err := copyS3Object(context.TODO(), mockClient, mockClient, "bucket", "bucket", target, nil); err != nil {
+		t.Fatalf("copyS3Object returned an error: %v", err)
+	}
+
+	expected := []string{"bytes=0-3", "bytes=4-7", "bytes=8-9"}
+	if len(ranges) != len(expected) {
+		t.Fatalf("expected ranges %v, got %v", expected, ranges)
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Errorf("part %d: expected range %s, got %s", i+1, expected[i], ranges[i])
+		}
+	}
+	if *created.ContentType != "text/plain" || created.Metadata["owner"] != "data-team" {
+		t.Error("expected the content type and metadata to be carried over")
+	}
+	if *created.Tagging != "project=a+b" {
+		t.Errorf("expected the tags to be carried over, got %s", *created.Tagging)
+	}
+	if created.StorageClass != types.StorageClassIntelligentTiering {
+		t.Errorf("expected the storage class to be kept, got %q", created.StorageClass)
+	}
+	if created.ServerSideEncryption != types.ServerSideEncryptionAwsKms || aws.ToString(created.SSEKMSKeyId) != "key-1" {
+		t.Errorf("expected the KMS encryption to be kept, got %q with key %q", created.ServerSideEncryption, aws.ToString(created.SSEKMSKeyId))
+	}
+	if len(completed.MultipartUpload.Parts) != 3 {
+		t.Errorf("expected 3 completed parts, got %d", len(completed.MultipartUpload.Parts))
+	}
+}
+
+func TestTransferS3ObjectMove(t *testing.T) {
+	clearCache()
+	var deleted []string
+	var copyErr error
+	mockClient := &mockS3Client{
+		GetBucketLocationFunc: func(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+			return nil, errors.New("no region") // fall back to the default client
+		},
+		HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{}, nil
+		},
+		CopyObjectFunc: func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+			return &s3.CopyObjectOutput{}, copyErr
+		},
+		DeleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			for _, object := range params.Delete.Objects {
+				deleted = append(deleted, *object.Key)
+			}
+			return &s3.DeleteObjectsOutput{}, nil
+		},
+	}
+	clientManager := NewClientManager(mockClient)
+	target := copyTarget{srcKey: "a.txt", destKey: "b/a.txt", size: 1}
+
+	if err := transferS3Object(context.TODO(), clientManager, "bucket", "bucket", target, false, nil); err != nil {
+		t.Fatalf("copy returned an error: %v", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("expected a copy to keep the source, deleted %v", deleted)
+	}
+
+	if err := transferS3Object(context.TODO(), clientManager, "bucket", "bucket", target, true, nil); err != nil {
+		t.Fatalf("move returned an error: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "a.txt" {
+		t.Errorf("expected the source to be deleted after moving, deleted %v", deleted)
+	}
+
+	// A failed copy must not delete the source
+	deleted = nil
+	copyErr = errors.New("access denied")
+	if err := transferS3Object(context.TODO(), clientManager, "bucket", "bucket", target, true, nil); err == nil {
+		t.Fatal("expected the failed copy to be reported")
+	}
+	if len(deleted) != 0 {
+		t.Errorf("expected the source to be kept after a failed copy, deleted %v", deleted)
+	}
+}
+
+func TestPlanRename(t *testing.T) {
+	var existing []string
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			var contents []types.Object
+			for _, key := range existing {
+				if strings.HasPrefix(key, *params.Prefix) {
+					contents = append(contents, types.Object{Key: aws.String(key), Size: aws.Int64(1)})
+				}
+			}
+			if params.MaxKeys != nil && len(contents) > int(*params.MaxKeys) {
+				contents = contents[:*params.MaxKeys]
+			}
+			return &s3.ListObjectsV2Output{Contents: contents}, nil
+		},
+	}
+
+	existing = []string{"data/old/", "data/old/a.txt", "data/old/sub/b.txt", "data/report.csv", "data/report.csv.bak"}
+
+	targets, err := planRename(context.TODO(), mockClient, "bucket", ObjectEntry{Key: "data/old/", IsDirectory: true}, "new")
+	if err != nil {
+		t.Fatalf("planRename returned an error: %v", err)
+	}
+	expected := map[string]string{
+		"data/old/":          "data/new/",
+		"data/old/a.txt":     "data/new/a.txt",
+		"data/old/sub/b.txt": "data/new/sub/b.txt",
+	}
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %d targets, got %d", len(expected), len(targets))
+	}
+	for _, target := range targets {
+		if expected[target.srcKey] != target.destKey {
+			t.Errorf("expected %s to be renamed to %s, got %s", target.srcKey, expected[target.srcKey], target.destKey)
+		}
+	}
+
+	targets, err = planRename(context.TODO(), mockClient, "bucket", ObjectEntry{Key: "data/report.csv", Size: 1}, "summary.csv")
+	if err != nil {
+		t.Fatalf("planRename returned an error: %v", err)
+	}
+	if len(targets) != 1 || targets[0].destKey != "data/summary.csv" {
+		t.Errorf("expected a single rename to data/summary.csv, got %+v", targets)
+	}
+
+	if _, err := planRename(context.TODO(), mockClient, "bucket", ObjectEntry{Key: "data/other.csv", Size: 1}, "report.csv"); err == nil {
+		t.Error("expected renaming onto an existing object to fail")
+	}
+	if _, err := planRename(context.TODO(), mockClient, "bucket", ObjectEntry{Key: "data/x/", IsDirectory: true}, "old"); err == nil {
+		t.Error("expected renaming onto an existing directory to fail")
+	}
+
+	// A longer key sharing the new name as a prefix is not a conflict
+	existing = []string{"data/report.csv.bak"}
+	if _, err := planRename(context.TODO(), mockClient, "bucket", ObjectEntry{Key: "data/x", Size: 1}, "report.csv"); err != nil {
+		t.Errorf("expected no conflict with a longer key, got %v", err)
+	}
+
+	for _, name := range []string{"", "a/b", ".."} {
+		if _, err := planRename(context.TODO(), mockClient, "bucket", ObjectEntry{Key: "data/x", Size: 1}, name); err == nil {
+			t.Errorf("expected name %q to be rejected", name)
+		}
+	}
+}
+
+func TestRenameS3Objects(t *testing.T) {
+	var mu sync.Mutex
+	var copied, deleted []string
+	failKey := ""
+	mockClient := &mockS3Client{
+		HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{}, nil
+		},
+		CopyObjectFunc: func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+			if params.MetadataDirective != types.MetadataDirectiveCopy || params.TaggingDirective != types.TaggingDirectiveCopy {
+				t.Error("expected metadata and tags to be copied")
+			}
+			if *params.Key == failKey {
+				return nil, errors.New("access denied")
+			}
+			mu.Lock()
+			copied = append(copied, *params.Key)
+			mu.Unlock()
+			return &s3.CopyObjectOutput{}, nil
+		},
+		DeleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			for _, object := range params.Delete.Objects {
+				deleted = append(deleted, *object.Key)
+			}
+			return &s3.DeleteObjectsOutput{}, nil
+		},
+	}
+
+	targets := []copyTarget{
+		{srcKey: "old/a", destKey: "new/a", size: 1},
+		{srcKey: "old/b", destKey: "new/b", size: 2},
+	}
+	var lastObjects int
+	var lastBytes int64
+	err := renameS3Objects(context.TODO(), mockClient, "bucket", targets, func(objectsDone, objectsTotal int, current, total int64) {
+		mu.Lock()
+		lastObjects, lastBytes = objectsDone, current
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("renameS3Objects returned an error: %v", err)
+	}
+	if len(copied) != 2 || len(deleted) != 2 {
+		t.Errorf("expected 2 copies and 2 deletes, got %v and %v", copied, deleted)
+	}
+	if lastObjects != 2 || lastBytes != 3 {
+		t.Errorf("expected progress to reach 2 objects and 3 bytes, got %d and %d", lastObjects, lastBytes)
+	}
+
+	// If a copy fails the originals are kept
+	deleted = nil
+	failKey = "new/b"
+	if err := renameS3Objects(context.TODO(), mockClient, "bucket", targets, nil); err == nil {
+		t.Fatal("expected the failed copy to be reported")
+	}
+	if len(deleted) != 0 {
+		t.Errorf("expected no originals to be deleted, got %v", deleted)
+	}
+}
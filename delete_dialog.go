@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/rivo/tview"
+)
+
+// maxListedEntries limits how many entry names a confirmation dialog lists
+const maxListedEntries = 10
+
+// describeEntries lists up to maxListedEntries entry keys, one per line
+func describeEntries(entries []ObjectEntry) string {
+	var lines []string
+	for i, entry := range entries {
+		if i == maxListedEntries {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(entries)-maxListedEntries))
+			break
+		}
+		lines = append(lines, entry.Key)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// showDeleteDialog resolves the objects covered by entries (recursively for
+// directories), asks for confirmation showing their count and total size, and
+// deletes them while showing progress. onDone is called on the UI goroutine
+// with a status message once the dialog is dismissed or the deletion ends.
+func showDeleteDialog(app *tview.Application, clientManager *ClientManager, bucketName string, entries []ObjectEntry, onDone func(message string)) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	scanModal := tview.NewModal().
+		SetText(fmt.Sprintf("Counting objects to delete in s3://%s ...", bucketName)).
+		AddButtons([]string{"Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			cancel()
+			onDone("Delete cancelled")
+		})
+	app.SetRoot(scanModal, true)
+
+	go func() {
+		var objects []types.Object
+		bucketClient, err := clientManager.GetClientForBucket(ctx, bucketName)
+		if err == nil {
+			objects, err = collectEntryObjects(ctx, bucketClient, bucketName, entries)
+		}
+
+		app.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				// Already dismissed through the Cancel button
+				return
+			}
+			if err != nil {
+				cancel()
+				onDone(fmt.Sprintf("Delete failed: %v", err))
+				return
+			}
+			if len(objects) == 0 {
+				cancel()
+				onDone("Nothing to delete")
+				return
+			}
+
+			var totalBytes int64
+			for _, object := range objects {
+				if object.Size != nil {
+					totalBytes += *object.Size
+				}
+			}
+
+			confirmText := fmt.Sprintf("Delete %d object(s) totalling %s from s3://%s?\n\n%s\n\nThis cannot be undone.",
+				len(objects), formatBytes(totalBytes), bucketName, describeEntries(entries))
+
+			confirmModal := showConfirmDialog(confirmText, "Delete", func() {
+				progressModal, updateProgress := showProgressWindow(app, "Deleting", fmt.Sprintf("%d object(s)", len(objects)), cancel)
+				app.SetRoot(progressModal, true)
+
+				go func() {
+					defer cancel()
+					deleted, err := deleteS3Objects(ctx, bucketClient, bucketName, objects, func(deleted int, deletedBytes int64) {
+						updateProgress(deletedBytes, totalBytes)
+					})
+
+					app.QueueUpdateDraw(func() {
+						if ctx.Err() != nil {
+							onDone(fmt.Sprintf("Delete cancelled after %d object(s)", deleted))
+						} else if err != nil {
+							onDone(fmt.Sprintf("Delete failed: %v", err))
+						} else {
+							onDone(fmt.Sprintf("Deleted %d object(s)", deleted))
+						}
+					})
+				}()
+			}, func() {
+				cancel()
+				onDone("Delete cancelled")
+			})
+			app.SetRoot(confirmModal, true)
+		})
+	}()
+}
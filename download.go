@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// downloadWorkers is the number of files downloaded concurrently
+const downloadWorkers = 4
+
+// downloadTarget is an object and the local path it will be written to
+type downloadTarget struct {
+	key       string
+	localPath string
+	size      int64
+	etag      string
+}
+
+// collisionPolicy decides what happens when a download would overwrite an
+// existing local file
+type collisionPolicy string
+
+const (
+	collisionOverwrite   collisionPolicy = "overwrite"
+	collisionSkip        collisionPolicy = "skip"
+	collisionRename      collisionPolicy = "rename"
+	collisionIfDifferent collisionPolicy = "if-different"
+)
+
+// collisionPolicies lists the policies in the order they are offered, with
+// the label shown for each
+var collisionPolicies = []struct {
+	policy collisionPolicy
+	label  string
+}{
+	{collisionOverwrite, "Overwrite"},
+	{collisionSkip, "Skip"},
+	{collisionRename, "Rename with suffix"},
+	{collisionIfDifferent, "Overwrite only if different"},
+}
+
+// downloadOptions controls how downloads treat existing and partial files
+type downloadOptions struct {
+	policy      collisionPolicy
+	keepPartial bool
+}
+
+// downloadSummary counts the outcome of a batch download
+type downloadSummary struct {
+	downloaded int
+	skipped    int
+}
+
+// fileMD5 returns the hex encoded MD5 digest of the file at path
+func fileMD5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sameAsLocal reports whether the local file at path appears to hold the same
+// content as target. Sizes are compared first; for objects uploaded in a
+// single part the ETag is the MD5 of the content and is compared as well.
+// Multipart ETags cannot be recomputed without the part size, so for those
+// matching sizes are taken as equal.
+func sameAsLocal(target downloadTarget, info os.FileInfo) bool {
+	if info.Size() != target.size {
+		return false
+	}
+	etag := strings.Trim(target.etag, `"`)
+	if etag == "" || strings.Contains(etag, "-") {
+		return true
+	}
+	sum, err := fileMD5(target.localPath)
+	return err == nil && sum == etag
+}
+
+// renamedPath returns the first "name (N).ext" variant of path that does not exist
+func renamedPath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// resolveCollision applies policy to target, returning the path to write to
+// or skip=true if the download should not happen
+func resolveCollision(target downloadTarget, policy collisionPolicy) (localPath string, skip bool) {
+	info, err := os.Stat(target.localPath)
+	if err != nil {
+		return target.localPath, false
+	}
+
+	switch policy {
+	case collisionSkip:
+		return "", true
+	case collisionRename:
+		return renamedPath(target.localPath), false
+	case collisionIfDifferent:
+		if sameAsLocal(target, info) {
+			return "", true
+		}
+	}
+	return target.localPath, false
+}
+
+// isDirectoryMarker reports whether key is a zero-byte "folder" object
+func isDirectoryMarker(key string) bool {
+	return strings.HasSuffix(key, "/")
+}
+
+// planDownloads maps the objects covered by entries to local paths under
+// destDir, keeping their layout relative to prefix. Directory entries are
+// expanded to every object below them.
+func planDownloads(ctx context.Context, client S3Client, bucketName, prefix string, entries []ObjectEntry, destDir string) ([]downloadTarget, error) {
+	objects, err := collectEntryObjects(ctx, client, bucketName, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []downloadTarget
+	for _, object := range objects {
+		rel := filepath.FromSlash(strings.TrimPrefix(*object.Key, prefix))
+
+		// Refuse keys such as "../x" that would escape the destination
+		if !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("refusing to download %s outside of %s", *object.Key, destDir)
+		}
+		localPath := filepath.Join(destDir, rel)
+
+		var size int64
+		if object.Size != nil {
+			size = *object.Size
+		}
+		var etag string
+		if object.ETag != nil {
+			etag = *object.ETag
+		}
+		targets = append(targets, downloadTarget{
+			key:       *object.Key,
+			localPath: localPath,
+			size:      size,
+			etag:      etag,
+		})
+	}
+	return targets, nil
+}
+
+// downloadTargets downloads targets using a bounded pool of workers, calling
+// onProgress with the aggregate number of files and bytes done. Large
+// objects are fetched as concurrent, resumable byte ranges. Existing
+// local files are handled according to the collision policy in options, and
+// directory markers only create their local directory. A failed file does
+// not stop the others; the returned error describes the failures, if any.
+// Cancelling ctx aborts the files in flight and starts no new ones.
+func downloadTargets(ctx context.Context, clientManager *ClientManager, bucketName string, targets []downloadTarget, options downloadOptions, onProgress func(filesDone, filesTotal int, current, total int64)) (downloadSummary, error) {
+	var totalBytes int64
+	for _, target := range targets {
+		totalBytes += target.size
+	}
+
+	var bytesDone int64
+	var filesDone, filesSkipped int32
+	report := func() {
+		if onProgress != nil {
+			onProgress(int(atomic.LoadInt32(&filesDone)), len(targets), atomic.LoadInt64(&bytesDone), totalBytes)
+		}
+	}
+
+	var mu sync.Mutex
+	var failures []error
+
+	jobs := make(chan downloadTarget)
+	var wg sync.WaitGroup
+	for i := 0; i < downloadWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				var err error
+				if isDirectoryMarker(target.key) {
+					err = os.MkdirAll(target.localPath, 0755)
+				} else if localPath, skip := resolveCollision(target, options.policy); skip {
+					atomic.AddInt32(&filesSkipped, 1)
+					atomic.AddInt64(&bytesDone, target.size)
+				} else {
+					var last int64
+					onFileProgress := func(current, total int64) {
+						atomic.AddInt64(&bytesDone, current-last)
+						last = current
+						report()
+					}
+					if target.size >= rangedDownloadThreshold {
+						var client S3Client
+						client, err = clientManager.GetClientForBucket(ctx, bucketName)
+						if err == nil {
+							err = downloadRanged(ctx, client, bucketName, target.key, localPath, target.size, target.etag, options.keepPartial, onFileProgress)
+						}
+					} else {
+						err = downloadFile(ctx, clientManager, bucketName, target.key, localPath, options.keepPartial, onFileProgress)
+					}
+				}
+
+				if err != nil {
+					if ctx.Err() != nil {
+						// Cancelled, not failed
+						continue
+					}
+					mu.Lock()
+					failures = append(failures, fmt.Errorf("%s: %w", target.key, err))
+					mu.Unlock()
+					continue
+				}
+				atomic.AddInt32(&filesDone, 1)
+				report()
+			}
+		}()
+	}
+
+dispatch:
+	for _, target := range targets {
+		select {
+		case jobs <- target:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	skipped := int(atomic.LoadInt32(&filesSkipped))
+	summary := downloadSummary{
+		downloaded: int(atomic.LoadInt32(&filesDone)) - skipped,
+		skipped:    skipped,
+	}
+	if len(failures) > 0 {
+		return summary, fmt.Errorf("%d file(s) failed, first error: %w", len(failures), failures[0])
+	}
+	return summary, ctx.Err()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// expandHome replaces a leading "~" in path with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// showDownloadDialog asks for the download destination, what to do with
+// files that already exist there and whether to keep partially downloaded
+// files. onSubmit receives the destination with "~" expanded.
+func showDownloadDialog(label, defaultDir string, defaults downloadOptions, onSubmit func(destDir string, options downloadOptions), onCancel func()) tview.Primitive {
+	destDir := defaultDir
+	keepPartial := defaults.keepPartial
+
+	var options []string
+	initial := 0
+	for i, p := range collisionPolicies {
+		options = append(options, p.label)
+		if p.policy == defaults.policy {
+			initial = i
+		}
+	}
+	policy := collisionPolicies[initial].policy
+
+	form := tview.NewForm().
+		AddInputField("Destination", destDir, 50, nil, func(text string) {
+			destDir = text
+		}).
+		AddDropDown("If file exists", options, initial, func(option string, optionIndex int) {
+			policy = collisionPolicies[optionIndex].policy
+		}).
+		AddCheckbox("Keep partial files (resume later)", keepPartial, func(checked bool) {
+			keepPartial = checked
+		})
+	form.AddButton("Download", func() {
+		onSubmit(expandHome(strings.TrimSpace(destDir)), downloadOptions{policy: policy, keepPartial: keepPartial})
+	}).
+		AddButton("Cancel", onCancel).
+		SetCancelFunc(onCancel)
+	form.SetBorder(true).
+		SetTitle(" Download " + label + " ")
+
+	return centered(form, 70, 11)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestPlanDownloads(t *testing.T) {
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("data/logs/"), Size: aws.Int64(0)},
+					{Key: aws.String("data/logs/a.log"), Size: aws.Int64(4)},
+					{Key: aws.String("data/logs/2024/b.log"), Size: aws.Int64(6)},
+				},
+			}, nil
+		},
+	}
+
+	entries := []ObjectEntry{
+		{Key: "data/logs/", IsDirectory: true},
+		{Key: "data/readme.txt", Size: 2},
+	}
+	targets, err := planDownloads(context.TODO(), mockClient, "test-bucket", "data/", entries, "out")
+	if err != nil {
+		t.Fatalf("planDownloads returned an error: %v", err)
+	}
+
+	expected := map[string]string{
+		"data/logs/":           filepath.Join("out", "logs"),
+		"data/logs/a.log":      filepath.Join("out", "logs", "a.log"),
+		"data/logs/2024/b.log": filepath.Join("out", "logs", "2024", "b.log"),
+		"data/readme.txt":      filepath.Join("out", "readme.txt"),
+	}
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %d targets, got %d", len(expected), len(targets))
+	}
+	for _, target := range targets {
+		if target.localPath != expected[target.key] {
+			t.Errorf("expected '%s' to be written to '%s', got '%s'", target.key, expected[target.key], target.localPath)
+		}
+	}
+}
+
+func TestPlanDownloadsRejectsEscapingKeys(t *testing.T) {
+	entries := []ObjectEntry{{Key: "data/../../etc/passwd", Size: 1}}
+	if _, err := planDownloads(context.TODO(), &mockS3Client{}, "test-bucket", "data/", entries, "out"); err == nil {
+		t.Error("expected planDownloads to reject a key escaping the destination")
+	}
+}
+
+func TestResolveCollision(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "report.csv")
+	writeTestFile(t, existing, 3)
+
+	// MD5 of three zero bytes
+	const zeroesMD5 = `"693e9af84d3dfcc71e640e005bdc5e2e"`
+
+	missing := downloadTarget{key: "new.csv", localPath: filepath.Join(dir, "new.csv"), size: 3}
+	if path, skip := resolveCollision(missing, collisionSkip); skip || path != missing.localPath {
+		t.Errorf("expected a missing file to be downloaded as is, got %q (skip=%v)", path, skip)
+	}
+
+	target := downloadTarget{key: "report.csv", localPath: existing, size: 3, etag: zeroesMD5}
+
+	if path, skip := resolveCollision(target, collisionOverwrite); skip || path != existing {
+		t.Errorf("expected overwrite to keep the path, got %q (skip=%v)", path, skip)
+	}
+	if _, skip := resolveCollision(target, collisionSkip); !skip {
+		t.Error("expected skip policy to skip an existing file")
+	}
+	if path, skip := resolveCollision(target, collisionRename); skip || path != filepath.Join(dir, "report (1).csv") {
+		t.Errorf("expected rename to 'report (1).csv', got %q (skip=%v)", path, skip)
+	}
+	if _, skip := resolveCollision(target, collisionIfDifferent); !skip {
+		t.Error("expected identical content to be skipped")
+	}
+
+	changed := target
+	changed.etag = `"00000000000000000000000000000000"`
+	if _, skip := resolveCollision(changed, collisionIfDifferent); skip {
+		t.Error("expected a different ETag to be downloaded")
+	}
+
+	resized := target
+	resized.size = 4
+	if _, skip := resolveCollision(resized, collisionIfDifferent); skip {
+		t.Error("expected a different size to be downloaded")
+	}
+
+	multipart := target
+	multipart.etag = `"0123456789abcdef-2"`
+	if _, skip := resolveCollision(multipart, collisionIfDifferent); !skip {
+		t.Error("expected a multipart object of equal size to be skipped")
+	}
+}
+
+// cancellingReader serves data and cancels its context after the first read
+type cancellingReader struct {
+	cancel context.CancelFunc
+	reads  int
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	r.reads++
+	if r.reads == 1 {
+		r.cancel()
+	}
+	return copy(p, "partial content"), nil
+}
+
+func TestDownloadFileCancellation(t *testing.T) {
+	for _, keepPartial := range []bool{false, true} {
+		clearCache()
+		ctx, cancel := context.WithCancel(context.Background())
+		reader := &cancellingReader{cancel: cancel}
+		mockClient := &mockS3Client{
+			GetBucketLocationFunc: func(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+				return nil, context.Canceled // fall back to the default client
+			},
+			GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{Body: io.NopCloser(reader), ContentLength: aws.Int64(1 << 30)}, nil
+			},
+		}
+
+		localPath := filepath.Join(t.TempDir(), "big.bin")
+		err := downloadFile(ctx, NewClientManager(mockClient), "test-bucket", "big.bin", localPath, keepPartial, nil)
+		if err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if reader.reads > 2 {
+			t.Errorf("expected reading to stop right after cancellation, got %d reads", reader.reads)
+		}
+
+		if _, err := os.Stat(localPath); !os.IsNotExist(err) {
+			t.Errorf("expected no file at the final path, got %v", err)
+		}
+		_, err = os.Stat(localPath + partialSuffix)
+		if keepPartial && err != nil {
+			t.Errorf("expected the partial file to be kept, got %v", err)
+		}
+		if !keepPartial && !os.IsNotExist(err) {
+			t.Errorf("expected the partial file to be removed, got %v", err)
+		}
+	}
+}
+
+func TestDownloadTargetsRanged(t *testing.T) {
+	clearCache()
+	withSmallChunks(t)
+	oldThreshold := rangedDownloadThreshold
+	rangedDownloadThreshold = 8
+	t.Cleanup(func() {
+		rangedDownloadThreshold = oldThreshold
+	})
+
+	content := []byte(strings.Repeat("0123456789abcdef", 8))
+	var requested []string
+	var mu sync.Mutex
+	mockClient := rangeServingClient(content, &requested, &mu)
+	mockClient.GetBucketLocationFunc = func(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+		return nil, context.Canceled // fall back to the default client
+	}
+
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+	targets := []downloadTarget{{key: "big.bin", localPath: localPath, size: int64(len(content)), etag: quotedMD5(content)}}
+
+	// The chunks are fetched concurrently, yet the aggregate progress must
+	// only ever grow
+	var progressMu sync.Mutex
+	var last int64
+	_, err := downloadTargets(context.TODO(), NewClientManager(mockClient), "test-bucket", targets, downloadOptions{}, func(filesDone, filesTotal int, current, total int64) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		if current < last || current > total {
+			t.Errorf("progress went from %d to %d of %d", last, current, total)
+		}
+		last = current
+	})
+	if err != nil {
+		t.Fatalf("downloadTargets returned an error: %v", err)
+	}
+
+	if last != int64(len(content)) {
+		t.Errorf("expected progress to end at %d, got %d", len(content), last)
+	}
+	if len(requested) != len(content)/4 {
+		t.Errorf("expected %d ranged requests, got %d", len(content)/4, len(requested))
+	}
+	data, _ := os.ReadFile(localPath)
+	if !bytes.Equal(data, content) {
+		t.Errorf("expected content %q, got %q", content, data)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// spinnerFrames animate work in progress, one frame per spinnerInterval
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+const spinnerInterval = 100 * time.Millisecond
+
+// spinnerFrame returns frame i of the spinner
+func spinnerFrame(i int) string {
+	return string(spinnerFrames[i%len(spinnerFrames)])
+}
+
+// dirUsage is what a directory holds: the total size and number of the
+// objects under its prefix, and the newest of their modification times
+type dirUsage struct {
+	size   int64
+	count  int
+	newest time.Time
+}
+
+// add counts object
+func (u *dirUsage) add(object types.Object) {
+	u.count++
+	if object.Size != nil {
+		u.size += *object.Size
+	}
+	if object.LastModified != nil && object.LastModified.After(u.newest) {
+		u.newest = *object.LastModified
+	}
+}
+
+// String formats the usage for the size column of a directory
+func (u dirUsage) String() string {
+	return fmt.Sprintf("%s (%d objects)", formatFileSize(u.size), u.count)
+}
+
+// usageKey identifies a directory in the cache of computed usages
+func usageKey(bucketName, prefix string) string {
+	return bucketName + "/" + prefix
+}
+
+// computeDirUsage adds up every object under prefix. onProgress is called
+// with the total so far after each listing page's worth of objects.
+func computeDirUsage(ctx context.Context, client S3Client, bucketName, prefix string, onProgress func(usage dirUsage)) (dirUsage, error) {
+	var usage dirUsage
+	err := walkS3Objects(ctx, client, bucketName, prefix, func(object types.Object) error {
+		usage.add(object)
+		if usage.count%findBatchSize == 0 {
+			onProgress(usage)
+		}
+		return nil
+	})
+	return usage, err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestDirUsage(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	var usage dirUsage
+	usage.add(types.Object{Key: aws.String("a/1"), Size: aws.Int64(1024), LastModified: &newer})
+	usage.add(types.Object{Key: aws.String("a/2"), Size: aws.Int64(1024), LastModified: &older})
+	usage.add(types.Object{Key: aws.String("a/")})
+
+	if usage.count != 3 {
+		t.Errorf("expected 3 objects, got %d", usage.count)
+	}
+	if usage.size != 2048 {
+		t.Errorf("expected 2048 bytes, got %d", usage.size)
+	}
+	if !usage.newest.Equal(newer) {
+		t.Errorf("expected newest modification %v, got %v", newer, usage.newest)
+	}
+	if got := usage.String(); got != "2.0 KB (3 objects)" {
+		t.Errorf("expected '2.0 KB (3 objects)', got '%s'", got)
+	}
+}
+
+func TestComputeDirUsage(t *testing.T) {
+	// Two full pages followed by a short one
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if params.Delimiter != nil {
+				t.Errorf("expected an undelimited listing, got delimiter '%s'", *params.Delimiter)
+			}
+			if *params.Prefix != "logs/" {
+				t.Errorf("expected prefix 'logs/', got '%s'", *params.Prefix)
+			}
+			n := 0
+			if params.ContinuationToken != nil {
+				fmt.Sscan(*params.ContinuationToken, &n)
+			}
+			if n == 2 {
+				return &s3.ListObjectsV2Output{
+					Contents: []types.Object{{Key: aws.String("logs/last"), Size: aws.Int64(5)}},
+				}, nil
+			}
+			var objects []types.Object
+			for i := 0; i < findBatchSize; i++ {
+				objects = append(objects, types.Object{Key: aws.String(fmt.Sprintf("logs/%d/%d", n, i)), Size: aws.Int64(1)})
+			}
+			return &s3.ListObjectsV2Output{
+				Contents:              objects,
+				IsTruncated:           aws.Bool(true),
+				NextContinuationToken: aws.String(fmt.Sprint(n + 1)),
+			}, nil
+		},
+	}
+
+	var progress []int
+	usage, err := computeDirUsage(context.TODO(), mockClient, "test-bucket", "logs/", func(partial dirUsage) {
+		progress = append(progress, partial.count)
+	})
+	if err != nil {
+		t.Fatalf("computeDirUsage returned an error: %v", err)
+	}
+
+	if usage.count != 2*findBatchSize+1 {
+		t.Errorf("expected %d objects, got %d", 2*findBatchSize+1, usage.count)
+	}
+	if usage.size != int64(2*findBatchSize+5) {
+		t.Errorf("expected %d bytes, got %d", 2*findBatchSize+5, usage.size)
+	}
+	if len(progress) != 2 || progress[0] != findBatchSize || progress[1] != 2*findBatchSize {
+		t.Errorf("expected progress after each full page, got %v", progress)
+	}
+}
+
+func TestComputeDirUsageError(t *testing.T) {
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			return nil, errors.New("access denied")
+		},
+	}
+
+	_, err := computeDirUsage(context.TODO(), mockClient, "test-bucket", "logs/", func(dirUsage) {})
+	if err == nil || err.Error() != "access denied" {
+		t.Errorf("expected the listing error, got %v", err)
+	}
+}
+
+func TestSpinnerFrame(t *testing.T) {
+	if spinnerFrame(0) != spinnerFrame(len(spinnerFrames)) {
+		t.Errorf("expected the spinner to wrap around")
+	}
+	if spinnerFrame(0) == spinnerFrame(1) {
+		t.Errorf("expected consecutive frames to differ")
+	}
+}
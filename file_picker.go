@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// addLocalChildren adds the entries of the directory at path to node.
+// Directories are listed first and expanded lazily when selected.
+func addLocalChildren(node *tview.TreeNode, path string) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		node.AddChild(tview.NewTreeNode("(" + err.Error() + ")").SetColor(tcell.ColorRed).SetSelectable(false))
+		return
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].IsDir() && !entries[j].IsDir()
+	})
+
+	for _, entry := range entries {
+		childPath := filepath.Join(path, entry.Name())
+		child := tview.NewTreeNode(entry.Name()).SetReference(childPath)
+		if entry.IsDir() {
+			child.SetText(entry.Name() + "/").SetColor(tcell.ColorBlue)
+		}
+		node.AddChild(child)
+	}
+}
+
+// showFilePicker displays a tree of the local filesystem rooted at root.
+// Enter on a file, or 'u' on any entry, calls onSelect with its path; Enter
+// on a directory expands or collapses it. ESC calls onCancel.
+func showFilePicker(root string, onSelect func(path string), onCancel func()) *tview.TreeView {
+	rootNode := tview.NewTreeNode(root + "/").
+		SetReference(root).
+		SetColor(tcell.ColorYellow)
+	addLocalChildren(rootNode, root)
+
+	tree := tview.NewTreeView().
+		SetRoot(rootNode).
+		SetCurrentNode(rootNode)
+	tree.SetBorder(true).
+		SetTitle(" Select file to upload (Enter: open/select, u: upload file or directory, ESC: cancel) ")
+
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		path, ok := node.GetReference().(string)
+		if !ok {
+			return
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		if !info.IsDir() {
+			onSelect(path)
+			return
+		}
+
+		// Load directory contents on first expansion
+		if len(node.GetChildren()) == 0 {
+			addLocalChildren(node, path)
+			node.SetExpanded(true)
+		} else {
+			node.SetExpanded(!node.IsExpanded())
+		}
+	})
+
+	tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			onCancel()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'u' {
+			if path, ok := tree.GetCurrentNode().GetReference().(string); ok {
+				onSelect(path)
+			}
+			return nil
+		}
+		return event
+	})
+
+	return tree
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// filterMode selects how a filter pattern is matched against names
+type filterMode int
+
+const (
+	filterSubstring filterMode = iota
+	filterGlob
+	filterRegex
+)
+
+func (m filterMode) String() string {
+	switch m {
+	case filterGlob:
+		return "glob"
+	case filterRegex:
+		return "regex"
+	}
+	return "substring"
+}
+
+// next returns the mode after m, wrapping around
+func (m filterMode) next() filterMode {
+	return (m + 1) % 3
+}
+
+// entryFilter matches names against a pattern. A nil filter matches everything.
+type entryFilter struct {
+	mode    filterMode
+	pattern string
+	lower   string
+	re      *regexp.Regexp
+}
+
+// newEntryFilter compiles pattern for mode. An empty pattern returns a nil
+// filter. Substring matching ignores case; glob patterns must match the whole
+// name; regular expressions may match anywhere in it.
+func newEntryFilter(mode filterMode, pattern string) (*entryFilter, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	f := &entryFilter{mode: mode, pattern: pattern}
+	switch mode {
+	case filterGlob:
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	case filterRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.re = re
+	default:
+		f.lower = strings.ToLower(pattern)
+	}
+	return f, nil
+}
+
+// matches reports whether name passes the filter
+func (f *entryFilter) matches(name string) bool {
+	if f == nil {
+		return true
+	}
+	switch f.mode {
+	case filterGlob:
+		ok, _ := path.Match(f.pattern, name)
+		return ok
+	case filterRegex:
+		return f.re.MatchString(name)
+	}
+	return strings.Contains(strings.ToLower(name), f.lower)
+}
+
+// filterEntries returns a new slice of the entries whose base names pass f
+func filterEntries(entries []ObjectEntry, f *entryFilter) []ObjectEntry {
+	var matches []ObjectEntry
+	for _, entry := range entries {
+		if f.matches(entryBaseName(entry.Key)) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// newFilterBar creates the input line for filtering a table as you type.
+// onChange is called with the new filter after every edit or mode change (or
+// with the error if the pattern does not compile) and returns the status
+// shown in the bar's title, e.g. the match count. Tab cycles the mode.
+// onDone is called with tcell.KeyEnter to keep the filter or tcell.KeyEscape
+// to clear it.
+func newFilterBar(mode filterMode, pattern string, onChange func(f *entryFilter, err error) string, onDone func(key tcell.Key)) *tview.InputField {
+	input := tview.NewInputField().SetText(pattern)
+	input.SetBorder(true)
+
+	update := func() {
+		input.SetLabel("Filter (" + mode.String() + "): ")
+		f, err := newEntryFilter(mode, input.GetText())
+		status := onChange(f, err)
+		input.SetTitle(" " + status + " (Tab: mode, Enter: keep, ESC: clear) ")
+	}
+
+	input.SetChangedFunc(func(text string) {
+		update()
+	})
+	input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyTab:
+			mode = mode.next()
+			update()
+		case tcell.KeyEnter, tcell.KeyEscape:
+			onDone(key)
+		}
+	})
+	update()
+
+	return input
+}
@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestEntryFilter(t *testing.T) {
+	tests := []struct {
+		mode    filterMode
+		pattern string
+		name    string
+		matches bool
+	}{
+		{filterSubstring, "Log", "access.log", true},
+		{filterSubstring, "log", "data.csv", false},
+		{filterGlob, "*.log", "access.log", true},
+		{filterGlob, "*.log", "access.log.gz", false},
+		{filterGlob, "2024-??-*", "2024-06-report", true},
+		{filterRegex, `^\d{4}-\d{2}`, "2024-06-report", true},
+		{filterRegex, `\.gz$`, "access.log", false},
+		{filterRegex, "LOG", "access.log", false},
+	}
+
+	for _, test := range tests {
+		f, err := newEntryFilter(test.mode, test.pattern)
+		if err != nil {
+			t.Fatalf("newEntryFilter(%s, %q) returned an error: %v", test.mode, test.pattern, err)
+		}
+		if actual := f.matches(test.name); actual != test.matches {
+			t.Errorf("%s filter %q on %q: expected %v, got %v", test.mode, test.pattern, test.name, test.matches, actual)
+		}
+	}
+}
+
+func TestEntryFilterEmptyAndInvalid(t *testing.T) {
+	f, err := newEntryFilter(filterRegex, "")
+	if err != nil || f != nil {
+		t.Errorf("expected an empty pattern to give no filter, got %v, %v", f, err)
+	}
+	if !f.matches("anything") {
+		t.Error("expected a nil filter to match everything")
+	}
+
+	if _, err := newEntryFilter(filterRegex, "("); err == nil {
+		t.Error("expected an invalid regular expression to be rejected")
+	}
+	if _, err := newEntryFilter(filterGlob, "["); err == nil {
+		t.Error("expected an invalid glob to be rejected")
+	}
+}
+
+func TestFilterEntries(t *testing.T) {
+	entries := []ObjectEntry{
+		{Key: "logs/", IsDirectory: true},
+		{Key: "logs/app.log"},
+		{Key: "logs/data.csv"},
+	}
+	f, _ := newEntryFilter(filterSubstring, "log")
+
+	matches := filterEntries(entries[1:], f)
+	if len(matches) != 1 || matches[0].Key != "logs/app.log" {
+		t.Errorf("expected only the base name to be matched, got %+v", matches)
+	}
+
+	all := filterEntries(entries, nil)
+	if len(all) != len(entries) {
+		t.Fatalf("expected a nil filter to keep all %d entries, got %d", len(entries), len(all))
+	}
+	all[0].Key = "changed"
+	if entries[0].Key == "changed" {
+		t.Error("expected filterEntries to return a copy")
+	}
+}
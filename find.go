@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// findBatchSize is how many scanned objects are reported together while a
+// search runs, one listing page's worth
+const findBatchSize = 1000
+
+// findCriteria selects objects in a search. Zero values leave a criterion out.
+type findCriteria struct {
+	name    *entryFilter
+	minSize int64
+	maxSize int64
+	after   time.Time
+	before  time.Time
+}
+
+// matches reports whether object meets every criterion
+func (c findCriteria) matches(object types.Object) bool {
+	if object.Key == nil || !c.name.matches(entryBaseName(*object.Key)) {
+		return false
+	}
+
+	var size int64
+	if object.Size != nil {
+		size = *object.Size
+	}
+	if size < c.minSize || (c.maxSize > 0 && size > c.maxSize) {
+		return false
+	}
+
+	if !c.after.IsZero() || !c.before.IsZero() {
+		if object.LastModified == nil {
+			return false
+		}
+		if !c.after.IsZero() && object.LastModified.Before(c.after) {
+			return false
+		}
+		if !c.before.IsZero() && !object.LastModified.Before(c.before) {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the criteria for the results title
+func (c findCriteria) String() string {
+	var parts []string
+	if c.name != nil {
+		parts = append(parts, fmt.Sprintf("%s %q", c.name.mode, c.name.pattern))
+	}
+	if c.minSize > 0 {
+		parts = append(parts, "≥ "+formatBytes(c.minSize))
+	}
+	if c.maxSize > 0 {
+		parts = append(parts, "≤ "+formatBytes(c.maxSize))
+	}
+	if !c.after.IsZero() {
+		parts = append(parts, "after "+c.after.Format(dateLayouts[0]))
+	}
+	if !c.before.IsZero() {
+		parts = append(parts, "before "+c.before.Format(dateLayouts[0]))
+	}
+	if len(parts) == 0 {
+		return "all objects"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseSize parses a size such as "512", "10K", "1.5MB" or "2GiB". Units are
+// powers of 1024, matching how sizes are displayed. An empty string is zero.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	number := strings.TrimRight(s, "BI")
+	multiplier := int64(1)
+	if n := len(number); n > 0 {
+		if exp := strings.IndexByte("KMGTPE", number[n-1]); exp >= 0 {
+			number = strings.TrimSpace(number[:n-1])
+			for i := 0; i <= exp; i++ {
+				multiplier *= 1024
+			}
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// dateLayouts are the accepted date formats, in local time
+var dateLayouts = []string{"2006-01-02 15:04", "2006-01-02"}
+
+// parseDate parses a date in one of dateLayouts. An empty string is the zero
+// time.
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or YYYY-MM-DD HH:MM", s)
+}
+
+// findS3Objects walks every key under prefix without a delimiter and passes
+// the objects meeting criteria to onBatch, together with the number of
+// objects scanned so far. Batches are reported every findBatchSize objects
+// and once more at the end, so results stream in while a large prefix is
+// searched.
+func findS3Objects(ctx context.Context, client S3Client, bucketName, prefix string, criteria findCriteria, onBatch func(matches []types.Object, scanned int)) error {
+	var pending []types.Object
+	scanned := 0
+
+	err := walkS3Objects(ctx, client, bucketName, prefix, func(object types.Object) error {
+		scanned++
+		if criteria.matches(object) {
+			pending = append(pending, object)
+		}
+		if scanned%findBatchSize == 0 {
+			onBatch(pending, scanned)
+			pending = nil
+		}
+		return nil
+	})
+
+	onBatch(pending, scanned)
+	return err
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"":      0,
+		"512":   512,
+		"10K":   10 * 1024,
+		"10kb":  10 * 1024,
+		"1.5M":  1536 * 1024,
+		"2GiB":  2 << 30,
+		" 1 T ": 1 << 40,
+	}
+	for input, expected := range tests {
+		size, err := parseSize(input)
+		if err != nil {
+			t.Errorf("parseSize(%q) returned an error: %v", input, err)
+		} else if size != expected {
+			t.Errorf("parseSize(%q) = %d, expected %d", input, size, expected)
+		}
+	}
+
+	for _, input := range []string{"abc", "-1", "10X", "M"} {
+		if _, err := parseSize(input); err == nil {
+			t.Errorf("parseSize(%q) should fail", input)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	date, err := parseDate("2024-03-01")
+	if err != nil || !date.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("unexpected date %v (%v)", date, err)
+	}
+	date, err = parseDate("2024-03-01 14:30")
+	if err != nil || !date.Equal(time.Date(2024, 3, 1, 14, 30, 0, 0, time.Local)) {
+		t.Errorf("unexpected date and time %v (%v)", date, err)
+	}
+	if date, err := parseDate(""); err != nil || !date.IsZero() {
+		t.Errorf("expected an empty date to be zero, got %v (%v)", date, err)
+	}
+	if _, err := parseDate("01/03/2024"); err == nil {
+		t.Error("expected an error for an unsupported date format")
+	}
+}
+
+func TestFindCriteriaMatches(t *testing.T) {
+	march := time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local)
+	object := types.Object{Key: aws.String("logs/2024/app.log"), Size: aws.Int64(2048), LastModified: &march}
+
+	criteria, err := parseFindCriteria(filterGlob, "*.log", "1K", "4K", "2024-03-01", "2024-04-01")
+	if err != nil {
+		t.Fatalf("parseFindCriteria returned an error: %v", err)
+	}
+	if !criteria.matches(object) {
+		t.Errorf("expected %s to match %s", *object.Key, criteria)
+	}
+
+	tests := []struct {
+		name                                     string
+		pattern, minSize, maxSize, after, before string
+	}{
+		{"name", "*.txt", "", "", "", ""},
+		{"too small", "", "4K", "", "", ""},
+		{"too large", "", "", "1K", "", ""},
+		{"too old", "", "", "", "2024-04-01", ""},
+		{"too new", "", "", "", "", "2024-03-15 12:00"},
+	}
+	for _, tt := range tests {
+		criteria, err := parseFindCriteria(filterGlob, tt.pattern, tt.minSize, tt.maxSize, tt.after, tt.before)
+		if err != nil {
+			t.Fatalf("%s: parseFindCriteria returned an error: %v", tt.name, err)
+		}
+		if criteria.matches(object) {
+			t.Errorf("%s: expected %s not to match %s", tt.name, *object.Key, criteria)
+		}
+	}
+
+	if !(findCriteria{}).matches(types.Object{Key: aws.String("a")}) {
+		t.Error("expected empty criteria to match everything")
+	}
+	if (findCriteria{after: march}).matches(types.Object{Key: aws.String("a")}) {
+		t.Error("expected an object without a date not to match a date range")
+	}
+	if _, err := parseFindCriteria(filterSubstring, "", "10M", "1M", "", ""); err == nil {
+		t.Error("expected an error for a max size below the min size")
+	}
+	if _, err := parseFindCriteria(filterRegex, "[", "", "", "", ""); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+}
+
+func TestFindS3Objects(t *testing.T) {
+	// Three full pages followed by a short one, with every tenth key a match
+	page := func(n int) []types.Object {
+		var objects []types.Object
+		for i := 0; i < findBatchSize; i++ {
+			key := fmt.Sprintf("data/%d/file-%d.txt", n, i)
+			if i%10 == 0 {
+				key = fmt.Sprintf("data/%d/file-%d.csv", n, i)
+			}
+			objects = append(objects, types.Object{Key: aws.String(key), Size: aws.Int64(1)})
+		}
+		return objects
+	}
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if params.Delimiter != nil {
+				t.Errorf("expected an undelimited listing, got delimiter '%s'", *params.Delimiter)
+			}
+			n := 0
+			if params.ContinuationToken != nil {
+				fmt.Sscan(*params.ContinuationToken, &n)
+			}
+			if n == 3 {
+				return &s3.ListObjectsV2Output{
+					Contents: []types.Object{{Key: aws.String("data/last.csv")}},
+				}, nil
+			}
+			return &s3.ListObjectsV2Output{
+				Contents:              page(n),
+				IsTruncated:           aws.Bool(true),
+				NextContinuationToken: aws.String(fmt.Sprint(n + 1)),
+			}, nil
+		},
+	}
+
+	criteria, _ := parseFindCriteria(filterGlob, "*.csv", "", "", "", "")
+	var batches, matches, scanned int
+	err := findS3Objects(context.TODO(), mockClient, "test-bucket", "data/", criteria, func(batch []types.Object, n int) {
+		batches++
+		matches += len(batch)
+		scanned = n
+	})
+	if err != nil {
+		t.Fatalf("findS3Objects returned an error: %v", err)
+	}
+
+	if batches != 4 {
+		t.Errorf("expected 4 batches, got %d", batches)
+	}
+	if scanned != 3*findBatchSize+1 {
+		t.Errorf("expected %d objects scanned, got %d", 3*findBatchSize+1, scanned)
+	}
+	if matches != 3*findBatchSize/10+1 {
+		t.Errorf("expected %d matches, got %d", 3*findBatchSize/10+1, matches)
+	}
+}
+
+func TestFindS3ObjectsError(t *testing.T) {
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			return nil, errors.New("access denied")
+		},
+	}
+
+	called := false
+	err := findS3Objects(context.TODO(), mockClient, "test-bucket", "", findCriteria{}, func(batch []types.Object, scanned int) {
+		called = true
+	})
+	if err == nil || err.Error() != "access denied" {
+		t.Errorf("expected the listing error, got %v", err)
+	}
+	if !called {
+		t.Error("expected a final batch to be reported")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// findModes are the name pattern modes offered in the find dialog, in the
+// order of the dropdown
+var findModes = []filterMode{filterGlob, filterSubstring, filterRegex}
+
+// showFindDialog asks for the criteria of a recursive search under location.
+// Invalid input is reported in the dialog's title and keeps it open.
+func showFindDialog(location string, onSubmit func(criteria findCriteria, summary string), onCancel func()) tview.Primitive {
+	var pattern, minSize, maxSize, after, before string
+	mode := findModes[0]
+
+	var modeNames []string
+	for _, m := range findModes {
+		modeNames = append(modeNames, m.String())
+	}
+
+	title := " Find in " + location + " "
+	form := tview.NewForm().
+		AddInputField("Name", "", 40, nil, func(text string) {
+			pattern = text
+		}).
+		AddDropDown("Match name as", modeNames, 0, func(option string, optionIndex int) {
+			mode = findModes[optionIndex]
+		}).
+		AddInputField("Min size (e.g. 10M)", "", 12, nil, func(text string) {
+			minSize = text
+		}).
+		AddInputField("Max size", "", 12, nil, func(text string) {
+			maxSize = text
+		}).
+		AddInputField("Modified after", "", 17, nil, func(text string) {
+			after = text
+		}).
+		AddInputField("Modified before", "", 17, nil, func(text string) {
+			before = text
+		})
+	form.AddButton("Find", func() {
+		criteria, err := parseFindCriteria(mode, pattern, minSize, maxSize, after, before)
+		if err != nil {
+			form.SetTitle(fmt.Sprintf(" %v ", err)).SetTitleColor(tcell.ColorRed)
+			return
+		}
+		onSubmit(criteria, criteria.String())
+	}).
+		AddButton("Cancel", onCancel).
+		SetCancelFunc(onCancel)
+	form.SetBorder(true).
+		SetTitle(title)
+
+	return centered(form, 70, 17)
+}
+
+// parseFindCriteria builds search criteria from the find dialog's fields
+func parseFindCriteria(mode filterMode, pattern, minSize, maxSize, after, before string) (findCriteria, error) {
+	var criteria findCriteria
+	var err error
+
+	if criteria.name, err = newEntryFilter(mode, strings.TrimSpace(pattern)); err != nil {
+		return criteria, fmt.Errorf("invalid %s pattern: %v", mode, err)
+	}
+	if criteria.minSize, err = parseSize(minSize); err != nil {
+		return criteria, err
+	}
+	if criteria.maxSize, err = parseSize(maxSize); err != nil {
+		return criteria, err
+	}
+	if criteria.maxSize > 0 && criteria.maxSize < criteria.minSize {
+		return criteria, fmt.Errorf("max size is below min size")
+	}
+	if criteria.after, err = parseDate(after); err != nil {
+		return criteria, err
+	}
+	if criteria.before, err = parseDate(before); err != nil {
+		return criteria, err
+	}
+	return criteria, nil
+}
+
+// findResults is the table listing the matches of a running or finished
+// search, with keys shown relative to the searched prefix
+type findResults struct {
+	table   *tview.Table
+	prefix  string
+	summary string
+	keys    []string
+	scanned int
+}
+
+// newFindResults creates an empty results table for a search under prefix
+func newFindResults(bucketName, prefix, summary string) *findResults {
+	r := &findResults{
+		table:   tview.NewTable().SetBorders(false).SetSelectable(true, false),
+		prefix:  prefix,
+		summary: summary,
+	}
+	r.table.SetBorder(true)
+	r.table.SetCell(0, 0, tview.NewTableCell("Key").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	r.table.SetCell(0, 1, tview.NewTableCell("Size").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	r.table.SetCell(0, 2, tview.NewTableCell("Modified").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	r.setTitle(fmt.Sprintf("Searching s3://%s/%s…", bucketName, prefix))
+	return r
+}
+
+// setTitle shows status together with the search summary and key help
+func (r *findResults) setTitle(status string) {
+	r.table.SetTitle(fmt.Sprintf(" %s %d scanned, %d matches [%s] (Enter: view, 'o': open folder, ESC: back) ", status, r.scanned, len(r.keys), r.summary))
+}
+
+// add appends a batch of matches and updates the scanned count
+func (r *findResults) add(matches []types.Object, scanned int) {
+	r.scanned = scanned
+	for _, object := range matches {
+		key := *object.Key
+		r.keys = append(r.keys, key)
+
+		var size int64
+		if object.Size != nil {
+			size = *object.Size
+		}
+		row := len(r.keys)
+		r.table.SetCell(row, 0, tview.NewTableCell(strings.TrimPrefix(key, r.prefix)))
+		r.table.SetCell(row, 1, tview.NewTableCell(formatFileSize(size)))
+		r.table.SetCell(row, 2, tview.NewTableCell(formatDate(object.LastModified)))
+	}
+	if len(r.keys) > 0 {
+		if row, _ := r.table.GetSelection(); row == 0 {
+			r.table.Select(1, 0)
+		}
+	}
+	r.setTitle("Searching…")
+}
+
+// finish shows that the search completed, or why it stopped
+func (r *findResults) finish(err error) {
+	if err != nil {
+		r.setTitle(fmt.Sprintf("Search failed: %v.", err))
+		return
+	}
+	r.setTitle("Done,")
+}
+
+// selectedKey returns the key of the selected match, if any
+func (r *findResults) selectedKey() (string, bool) {
+	row, _ := r.table.GetSelection()
+	if row > 0 && row-1 < len(r.keys) { // Skip header row
+		return r.keys[row-1], true
+	}
+	return "", false
+}
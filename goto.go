@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// maxGoToCompletions limits the entries offered in the go-to drop-down
+const maxGoToCompletions = 50
+
+// maxGoToPages limits the listing pages read for the folders to complete,
+// so that a prefix holding a huge number of objects does not hold up the
+// drop-down
+const maxGoToPages = 10
+
+// resolveGoTo turns the text entered in the go-to prompt into a bucket and
+// prefix. Besides s3:// URLs it accepts paths relative to the current
+// location, paths starting with "/" relative to the current bucket, and "."
+// and ".." segments. Outside a bucket the first segment names the bucket.
+// An empty bucket means the bucket list.
+func resolveGoTo(text, bucketName, prefix string) (string, string, error) {
+	text = strings.TrimSpace(text)
+
+	var path string
+	switch {
+	case text == "s3://":
+		return "", "", nil
+	case strings.HasPrefix(text, "s3://"):
+		bucket, p, err := parseS3URL(text)
+		if err != nil {
+			return "", "", err
+		}
+		path = bucket + "/" + p
+	case strings.HasPrefix(text, "/") && bucketName != "":
+		path = bucketName + "/" + text
+	default:
+		path = bucketName + "/" + prefix + text
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			segments = append(segments, segment)
+		}
+	}
+
+	if len(segments) == 0 {
+		return "", "", nil
+	}
+	if len(segments) == 1 {
+		return segments[0], "", nil
+	}
+	return segments[0], strings.Join(segments[1:], "/") + "/", nil
+}
+
+// splitCompletion splits the prompt text into the part naming the location
+// to complete in, up to and including the last "/", and the partial name
+// after it
+func splitCompletion(text string) (head, partial string) {
+	if text == "s3:/" || text == "s3:" {
+		return "s3://", ""
+	}
+	i := strings.LastIndex(text, "/")
+	return text[:i+1], text[i+1:]
+}
+
+// fuzzyMatch reports how well name matches pattern, ignoring case: 0 for a
+// prefix match, 1 for a substring match, 2 if the pattern's characters
+// appear in order, and -1 for no match
+func fuzzyMatch(pattern, name string) int {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(name, pattern):
+		return 0
+	case strings.Contains(name, pattern):
+		return 1
+	}
+
+	rest := name
+	for _, r := range pattern {
+		i := strings.IndexRune(rest, r)
+		if i < 0 {
+			return -1
+		}
+		rest = rest[i+len(string(r)):]
+	}
+	return 2
+}
+
+// fuzzyRank returns the names matching pattern, best matches first and
+// otherwise in their original order
+func fuzzyRank(names []string, pattern string) []string {
+	type match struct {
+		name  string
+		score int
+	}
+	var matches []match
+	for _, name := range names {
+		if score := fuzzyMatch(pattern, name); score >= 0 {
+			matches = append(matches, match{name, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score < matches[j].score
+	})
+
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = m.name
+	}
+	return result
+}
+
+// listGoToFolders lists the names of the folders directly under prefix for
+// completion, following at most maxGoToPages listing pages. truncated
+// reports whether there were more pages.
+func listGoToFolders(ctx context.Context, client S3Client, bucketName, prefix string) (names []string, truncated bool, err error) {
+	var continuationToken *string
+	for i := 0; i < maxGoToPages; i++ {
+		page, err := listS3ObjectsPage(ctx, client, bucketName, prefix, continuationToken)
+		if err != nil {
+			return names, false, err
+		}
+		for _, common := range page.CommonPrefixes {
+			names = append(names, entryBaseName(*common.Prefix))
+		}
+		if page.IsTruncated == nil || !*page.IsTruncated {
+			return names, false, nil
+		}
+		continuationToken = page.NextContinuationToken
+	}
+	return names, true, nil
+}
+
+// newGoToPrompt creates the go-to input. complete returns the completions
+// for the entered text; they are shown in a drop-down as you type, and Tab
+// accepts the only completion or opens the drop-down.
+func newGoToPrompt(initial string, complete func(text string) []string, onSubmit func(text string), onCancel func()) *tview.InputField {
+	input := newInputBox("Go to", "Path: ", initial, onSubmit, onCancel)
+	input.SetTitle(" Go to s3://bucket/prefix or a relative path (Tab: complete, Enter: go, ESC: cancel) ")
+	input.SetAutocompleteFunc(complete)
+	input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			onSubmit(input.GetText())
+		case tcell.KeyEscape:
+			onCancel()
+		case tcell.KeyTab:
+			if completions := complete(input.GetText()); len(completions) == 1 {
+				input.SetText(completions[0])
+			}
+			input.Autocomplete()
+		}
+	})
+	return input
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestResolveGoTo(t *testing.T) {
+	tests := []struct {
+		text, bucket, prefix   string
+		wantBucket, wantPrefix string
+	}{
+		{"s3://other/logs/2024", "data", "in/", "other", "logs/2024/"},
+		{"s3://other", "data", "in/", "other", ""},
+		{"s3://", "data", "in/", "", ""},
+		{"archive", "data", "in/", "data", "in/archive/"},
+		{"archive/2024/", "data", "in/", "data", "in/archive/2024/"},
+		{"../out", "data", "in/", "data", "out/"},
+		{"..", "data", "", "", ""},
+		{"./a//b", "data", "in/", "data", "in/a/b/"},
+		{"/top", "data", "in/deep/", "data", "top/"},
+		{"/", "data", "in/", "data", ""},
+		{"other/logs", "", "", "other", "logs/"},
+		{"/other", "", "", "other", ""},
+		{"  s3://b/p/  ", "", "", "b", "p/"},
+	}
+	for _, tt := range tests {
+		bucket, prefix, err := resolveGoTo(tt.text, tt.bucket, tt.prefix)
+		if err != nil {
+			t.Errorf("resolveGoTo(%q, %q, %q) returned an error: %v", tt.text, tt.bucket, tt.prefix, err)
+			continue
+		}
+		if bucket != tt.wantBucket || prefix != tt.wantPrefix {
+			t.Errorf("resolveGoTo(%q, %q, %q) = %q, %q, expected %q, %q", tt.text, tt.bucket, tt.prefix, bucket, prefix, tt.wantBucket, tt.wantPrefix)
+		}
+	}
+}
+
+func TestSplitCompletion(t *testing.T) {
+	tests := map[string][2]string{
+		"s3://bucket/lo": {"s3://bucket/", "lo"},
+		"s3://buc":       {"s3://", "buc"},
+		"s3:/":           {"s3://", ""},
+		"logs/20":        {"logs/", "20"},
+		"arch":           {"", "arch"},
+	}
+	for text, expected := range tests {
+		head, partial := splitCompletion(text)
+		if head != expected[0] || partial != expected[1] {
+			t.Errorf("splitCompletion(%q) = %q, %q, expected %q, %q", text, head, partial, expected[0], expected[1])
+		}
+	}
+}
+
+func TestFuzzyRank(t *testing.T) {
+	names := []string{"archive", "daily-reports", "Reports", "raw-exports", "logs"}
+
+	if result := strings.Join(fuzzyRank(names, "rep"), ","); result != "Reports,daily-reports,raw-exports" {
+		t.Errorf("unexpected ranking for 'rep': %s", result)
+	}
+	if result := strings.Join(fuzzyRank(names, "rxp"), ","); result != "raw-exports" {
+		t.Errorf("unexpected ranking for 'rxp': %s", result)
+	}
+	if result := fuzzyRank(names, ""); len(result) != len(names) {
+		t.Errorf("expected an empty pattern to match everything, got %v", result)
+	}
+	if result := fuzzyRank(names, "zzz"); len(result) != 0 {
+		t.Errorf("expected no matches, got %v", result)
+	}
+}
+
+// pagedFolderClient returns a mock client listing one folder per page, over
+// pages pages
+func pagedFolderClient(pages int) *mockS3Client {
+	return &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			page := 0
+			if params.ContinuationToken != nil {
+				fmt.Sscanf(*params.ContinuationToken, "%d", &page)
+			}
+			output := &s3.ListObjectsV2Output{
+				CommonPrefixes: []types.CommonPrefix{{Prefix: aws.String(fmt.Sprintf("%sf%d/", *params.Prefix, page))}},
+			}
+			if page+1 < pages {
+				output.IsTruncated = aws.Bool(true)
+				output.NextContinuationToken = aws.String(fmt.Sprint(page + 1))
+			}
+			return output, nil
+		},
+	}
+}
+
+func TestListGoToFolders(t *testing.T) {
+	names, truncated, err := listGoToFolders(context.TODO(), pagedFolderClient(3), "data", "in/")
+	if err != nil {
+		t.Fatalf("listGoToFolders returned an error: %v", err)
+	}
+	if truncated || !reflect.DeepEqual(names, []string{"f0", "f1", "f2"}) {
+		t.Errorf("expected the folders of all 3 pages, got %v (truncated %v)", names, truncated)
+	}
+
+	names, truncated, err = listGoToFolders(context.TODO(), pagedFolderClient(maxGoToPages+1), "data", "in/")
+	if err != nil {
+		t.Fatalf("listGoToFolders returned an error: %v", err)
+	}
+	if !truncated || len(names) != maxGoToPages {
+		t.Errorf("expected %d folders and the list truncated, got %v (truncated %v)", maxGoToPages, names, truncated)
+	}
+}
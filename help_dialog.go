@@ -16,11 +16,51 @@ func showHelpDialog(app *tview.Application) *tview.Modal {
   %-15s %s
   %-15s %s
   %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
 
 [cyan]File Operations:[-]
   %-15s %s
   %-15s %s
   %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+
+[cyan]Selection:[-]
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+
+[cyan]Transfers:[-]
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+
+[cyan]Panes and Tabs:[-]
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
+  %-15s %s
 
 [cyan]Application:[-]
   %-15s %s
@@ -33,8 +73,10 @@ func showHelpDialog(app *tview.Application) *tview.Modal {
 [cyan]Features:[-]
   • ASCII art preview for images
   • Gzip decompression for compressed files
-  • Progress window for downloads with cancel option
-  • Session state persistence
+  • Downloads and uploads run in a background queue
+  • Parallel, resumable downloads of large objects
+  • File actions apply to all marked entries
+  • Session state persistence, including open tabs, the selection and open file
   • Command line S3 URL support
 
 Press ESC or Enter to close this help.`,
@@ -42,9 +84,43 @@ Press ESC or Enter to close this help.`,
 		"[white]←/Backspace[-]", "Go back / up one level",
 		"[white]→/Enter[-]", "Enter directory / view file",
 		"[white]Ctrl+L[-]", "Refresh current view",
+		"[white][/] Alt+←/→[-]", "Go back/forward through visited locations",
+		"[white]g/:[-]", "Go to s3:// URL or relative path (Tab completes)",
+		"[white]B[-]", "Bookmark the current folder",
+		"[white]b[-]", "Open bookmarks (Enter: go, r: rename, x: delete)",
+		"[white]/[-]", "Filter buckets or entries (Tab: substring/glob/regex)",
+		"[white]s/S[-]", "Sort by next column / reverse order",
+		"[white]f[-]", "Find objects below here (results: o opens folder)",
+		"[white]T[-]", "Show the bucket as a tree (o opens in the listing)",
+		"[white]U[-]", "Show where the space here goes (Enter: break down)",
+		"[white]D[-]", "Compute the size of selected folders (again to stop)",
+		"[white]A[-]", "Compute folder sizes automatically on/off",
 		"[white]c[-]", "Copy S3 URL to clipboard",
 		"[white]C[-]", "Copy presigned URL to clipboard",
-		"[white]d[-]", "Download file to current directory",
+		"[white]d[-]", "Download file or directory to a chosen folder",
+		"[white]u[-]", "Upload local file or directory here",
+		"[white]x/Delete[-]", "Delete file or directory (with confirmation)",
+		"[white]y/m[-]", "Yank entries to copy/move them",
+		"[white]p[-]", "Paste yanked entries here",
+		"[white]r[-]", "Rename file or directory",
+		"[white]n[-]", "Create a folder here",
+		"[white]Space/v[-]", "Mark/unmark entry for batch operations",
+		"[white]*[-]", "Invert marks",
+		"[white]+/-[-]", "Mark/unmark entries matching a pattern",
+		"[white]ESC[-]", "Clear all marks",
+		"[white]t[-]", "Show/focus/hide the transfers panel",
+		"[white]p[-]", "Pause/resume the selected transfer (stop/restart if it cannot resume)",
+		"[white]r[-]", "Retry a failed or cancelled transfer",
+		"[white]x/Delete[-]", "Cancel the selected transfer",
+		"[white]c[-]", "Clear finished transfers",
+		"[white]+/-[-]", "Run more/fewer transfers at once",
+		"[white]Enter[-]", "Show transfer details",
+		"[white]|[-]", "Split into two panes / back to one",
+		"[white]Tab[-]", "Switch to the other pane",
+		"[white]F5/F6[-]", "Copy/move entries to the other pane",
+		"[white]L[-]", "Browse the local filesystem in this pane",
+		"[white]Ctrl+T/Ctrl+W[-]", "Open a tab here / close the tab",
+		"[white]{/}[-]", "Switch to the previous/next tab",
 		"[white]?[-]", "Show this help dialog",
 		"[white]Ctrl+C[-]", "Exit application (prints current S3 URL)",
 		"[white]ESC[-]", "Close dialogs / go back",
@@ -0,0 +1,72 @@
+package main
+
+// maxHistory limits how many locations are remembered for going back
+const maxHistory = 100
+
+// location is a place visited in the browser: a prefix in a bucket, or the
+// bucket list if bucket is empty, with the key last selected there
+type location struct {
+	bucket   string
+	prefix   string
+	selected string
+}
+
+// navHistory is a browser-like back/forward history of visited locations
+type navHistory struct {
+	entries []location
+	index   int // the current location, -1 before the first visit
+}
+
+// newNavHistory creates an empty history
+func newNavHistory() *navHistory {
+	return &navHistory{index: -1}
+}
+
+// current returns the current location, if any
+func (h *navHistory) current() (location, bool) {
+	if h.index < 0 {
+		return location{}, false
+	}
+	return h.entries[h.index], true
+}
+
+// visit records that bucket and prefix are shown. Visiting a new location
+// drops the forward history; showing the current location again, e.g. after
+// going back or refreshing, changes nothing.
+func (h *navHistory) visit(bucket, prefix string) {
+	if current, ok := h.current(); ok && current.bucket == bucket && current.prefix == prefix {
+		return
+	}
+
+	h.entries = append(h.entries[:h.index+1], location{bucket: bucket, prefix: prefix})
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	h.index = len(h.entries) - 1
+}
+
+// setSelected remembers the key selected at bucket and prefix, if that is
+// the current location
+func (h *navHistory) setSelected(bucket, prefix, key string) {
+	if current, ok := h.current(); ok && current.bucket == bucket && current.prefix == prefix {
+		h.entries[h.index].selected = key
+	}
+}
+
+// back moves to the previous location and returns it
+func (h *navHistory) back() (location, bool) {
+	if h.index <= 0 {
+		return location{}, false
+	}
+	h.index--
+	return h.entries[h.index], true
+}
+
+// forward moves to the next location and returns it
+func (h *navHistory) forward() (location, bool) {
+	if h.index+1 >= len(h.entries) {
+		return location{}, false
+	}
+	h.index++
+	return h.entries[h.index], true
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestNavHistory(t *testing.T) {
+	h := newNavHistory()
+	if _, ok := h.back(); ok {
+		t.Error("expected no history to go back to")
+	}
+
+	h.visit("", "")
+	h.setSelected("", "", "data")
+	h.visit("data", "")
+	h.visit("data", "logs/")
+	h.setSelected("data", "logs/", "logs/app.log")
+	h.visit("data", "logs/") // a refresh is not a new visit
+
+	loc, ok := h.back()
+	if !ok || loc.bucket != "data" || loc.prefix != "" {
+		t.Fatalf("expected to go back to data, got %+v", loc)
+	}
+	loc, _ = h.back()
+	if loc.bucket != "" || loc.selected != "data" {
+		t.Errorf("expected the bucket list with data selected, got %+v", loc)
+	}
+
+	loc, ok = h.forward()
+	if !ok || loc.bucket != "data" || loc.prefix != "" {
+		t.Errorf("expected to go forward to data, got %+v", loc)
+	}
+	h.visit(loc.bucket, loc.prefix)
+	loc, _ = h.forward()
+	if loc.prefix != "logs/" || loc.selected != "logs/app.log" {
+		t.Errorf("expected logs/ with its selection, got %+v", loc)
+	}
+	if _, ok := h.forward(); ok {
+		t.Error("expected no forward history at the newest location")
+	}
+
+	// Visiting a new location drops the forward history
+	h.back()
+	h.visit("other", "")
+	if _, ok := h.forward(); ok {
+		t.Error("expected the forward history to be dropped")
+	}
+	if loc, _ := h.back(); loc.bucket != "data" {
+		t.Errorf("expected to go back to data, got %+v", loc)
+	}
+
+	// setSelected only applies to the current location
+	h.setSelected("other", "", "x")
+	if loc, _ := h.current(); loc.selected == "x" {
+		t.Error("expected the selection of another location to be ignored")
+	}
+}
+
+func TestNavHistoryLimit(t *testing.T) {
+	h := newNavHistory()
+	for i := 0; i < maxHistory+10; i++ {
+		h.visit("bucket", string(rune('a'+i%26))+string(rune('a'+i/26))+"/")
+	}
+	if len(h.entries) != maxHistory {
+		t.Errorf("expected %d entries, got %d", maxHistory, len(h.entries))
+	}
+	steps := 0
+	for {
+		if _, ok := h.back(); !ok {
+			break
+		}
+		steps++
+	}
+	if steps != maxHistory-1 {
+		t.Errorf("expected to go back %d times, got %d", maxHistory-1, steps)
+	}
+}
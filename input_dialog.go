@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newInputBox creates a bordered single-line input. Enter calls onSubmit
+// with the entered text, ESC calls onCancel.
+func newInputBox(title, label, initial string, onSubmit func(text string), onCancel func()) *tview.InputField {
+	input := tview.NewInputField().
+		SetLabel(label).
+		SetText(initial)
+	input.SetBorder(true).
+		SetTitle(" " + title + " (Enter: confirm, ESC: cancel) ")
+
+	input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			onSubmit(input.GetText())
+		case tcell.KeyEscape:
+			onCancel()
+		}
+	})
+
+	return input
+}
+
+// showInputDialog displays a single-line input box centered on the screen.
+// Enter calls onSubmit with the entered text, ESC calls onCancel.
+func showInputDialog(title, label, initial string, onSubmit func(text string), onCancel func()) tview.Primitive {
+	return centered(newInputBox(title, label, initial, onSubmit, onCancel), 70, 3)
+}
+
+// centered places p in the middle of the screen at the given size
+func centered(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
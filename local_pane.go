@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// localEntry is a file or directory shown in a local filesystem pane
+type localEntry struct {
+	name    string
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+// listLocalDir lists the entries of dir, directories first, each group
+// sorted by name
+func listLocalDir(dir string) ([]localEntry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []localEntry
+	for _, dirEntry := range dirEntries {
+		entry := localEntry{name: dirEntry.Name(), isDir: dirEntry.IsDir()}
+		if info, err := dirEntry.Info(); err == nil {
+			entry.modTime = info.ModTime()
+			if !entry.isDir {
+				entry.size = info.Size()
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].isDir != entries[j].isDir {
+			return entries[i].isDir
+		}
+		return entries[i].name < entries[j].name
+	})
+	return entries, nil
+}
+
+// localPane browses a directory of the local filesystem, as the counterpart
+// of an S3 listing in the dual-pane layout
+type localPane struct {
+	flex    *tview.Flex
+	header  *tview.TextView
+	table   *tview.Table
+	dir     string
+	entries []localEntry
+	marked  map[string]bool
+}
+
+// newLocalPane creates a pane browsing dir. onExit is called when the user
+// leaves the local filesystem with 'L'.
+func newLocalPane(dir string, onExit func()) *localPane {
+	p := &localPane{
+		header: tview.NewTextView().SetTextAlign(tview.AlignCenter),
+		table:  tview.NewTable().SetBorders(false).SetSelectable(true, false),
+	}
+	p.table.SetBorder(true)
+	p.flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(p.header, 3, 1, false).
+		AddItem(p.table, 0, 1, true)
+
+	p.table.SetSelectionChangedFunc(func(row, column int) {
+		if row > 0 && row-1 < len(p.entries) { // Skip header row
+			p.header.SetText(filepath.Join(p.dir, p.entries[row-1].name))
+		}
+	})
+
+	p.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := p.table.GetSelection()
+		switch {
+		case event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyRight:
+			if row > 0 && row-1 < len(p.entries) && p.entries[row-1].isDir {
+				p.load(filepath.Join(p.dir, p.entries[row-1].name), "")
+			}
+		case event.Key() == tcell.KeyLeft:
+			if parent := filepath.Dir(p.dir); parent != p.dir {
+				previous := filepath.Base(p.dir)
+				p.load(parent, previous)
+			}
+		case event.Key() == tcell.KeyCtrlL:
+			p.refresh()
+		case event.Key() == tcell.KeyEscape && len(p.marked) > 0:
+			p.marked = make(map[string]bool)
+			p.render()
+		case event.Key() == tcell.KeyRune && (event.Rune() == ' ' || event.Rune() == 'v'):
+			if row > 0 && row-1 < len(p.entries) {
+				name := p.entries[row-1].name
+				if p.marked[name] {
+					delete(p.marked, name)
+				} else {
+					p.marked[name] = true
+				}
+				p.render()
+				if row < len(p.entries) {
+					p.table.Select(row+1, 0)
+				}
+			}
+		case event.Key() == tcell.KeyRune && event.Rune() == 'L':
+			onExit()
+		default:
+			return event
+		}
+		return nil
+	})
+
+	p.load(dir, "")
+	return p
+}
+
+// load lists dir, selecting the entry called selectName if there is one
+func (p *localPane) load(dir, selectName string) {
+	p.dir = dir
+	p.marked = make(map[string]bool)
+	p.header.SetText(dir)
+
+	entries, err := listLocalDir(dir)
+	p.entries = entries
+	p.render()
+	if err != nil {
+		p.table.SetCell(1, 0, tview.NewTableCell(fmt.Sprintf("Error: %v", err)).SetTextColor(tcell.ColorRed).SetSelectable(false))
+		return
+	}
+
+	selected := 1
+	for i, entry := range p.entries {
+		if entry.name == selectName {
+			selected = i + 1
+		}
+	}
+	p.table.Select(selected, 0)
+}
+
+// refresh lists the current directory again, keeping the selection
+func (p *localPane) refresh() {
+	selectName := ""
+	if row, _ := p.table.GetSelection(); row > 0 && row-1 < len(p.entries) {
+		selectName = p.entries[row-1].name
+	}
+	p.load(p.dir, selectName)
+}
+
+// render fills the table from the listed entries
+func (p *localPane) render() {
+	p.table.Clear()
+	p.table.SetCell(0, 0, tview.NewTableCell("Name").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	p.table.SetCell(0, 1, tview.NewTableCell("Size").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	p.table.SetCell(0, 2, tview.NewTableCell("Modified").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+
+	for i, entry := range p.entries {
+		name, size := entry.name, "DIR"
+		color := tview.Styles.PrimaryTextColor
+		if entry.isDir {
+			name += "/"
+			color = tcell.ColorBlue
+		} else {
+			size = formatFileSize(entry.size)
+		}
+		if p.marked[entry.name] {
+			name = "* " + name
+			color = tcell.ColorYellow
+		}
+		modTime := entry.modTime
+		p.table.SetCell(i+1, 0, tview.NewTableCell(name).SetTextColor(color))
+		p.table.SetCell(i+1, 1, tview.NewTableCell(size).SetTextColor(color))
+		p.table.SetCell(i+1, 2, tview.NewTableCell(formatDate(&modTime)).SetTextColor(color))
+	}
+
+	title := fmt.Sprintf(" Local: %s (Space: mark, 'L': back to S3) ", p.dir)
+	if len(p.marked) > 0 {
+		title = fmt.Sprintf(" Local: %s [%d marked] ", p.dir, len(p.marked))
+	}
+	p.table.SetTitle(title)
+}
+
+// selectedPaths returns the paths of the marked entries, or of the entry
+// under the cursor if nothing is marked
+func (p *localPane) selectedPaths() []string {
+	var paths []string
+	for _, entry := range p.entries {
+		if p.marked[entry.name] {
+			paths = append(paths, filepath.Join(p.dir, entry.name))
+		}
+	}
+	if len(paths) > 0 {
+		return paths
+	}
+	if row, _ := p.table.GetSelection(); row > 0 && row-1 < len(p.entries) {
+		return []string{filepath.Join(p.dir, p.entries[row-1].name)}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListLocalDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("hello"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "zdir"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	entries, err := listLocalDir(dir)
+	if err != nil {
+		t.Fatalf("listLocalDir returned error: %v", err)
+	}
+
+	want := []string{"zdir", "a.txt", "b.txt"}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(entries))
+	}
+	for i, name := range want {
+		if entries[i].name != name {
+			t.Errorf("Entry %d: expected %s, got %s", i, name, entries[i].name)
+		}
+	}
+	if !entries[0].isDir || entries[0].size != 0 {
+		t.Errorf("Expected zdir to be a directory without size, got %+v", entries[0])
+	}
+	if entries[1].isDir || entries[1].size != 5 {
+		t.Errorf("Expected a.txt to be a 5 byte file, got %+v", entries[1])
+	}
+
+	if _, err := listLocalDir(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected an error for a missing directory")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -125,24 +126,37 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-// downloadFile downloads a file from S3 to the current working directory
-func downloadFile(clientManager *ClientManager, bucketName, key string, onProgress func(current, total int64)) error {
+// partialSuffix is appended to the local path while a download is in progress
+const partialSuffix = ".part"
+
+// contextReader fails reads as soon as its context is cancelled, so a
+// cancelled download stops without waiting for the rest of the body
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.reader.Read(p)
+}
+
+// downloadFile downloads a file from S3 to localPath, creating its parent
+// directories as needed. The content is written to localPath+partialSuffix
+// and renamed once complete. If the download fails or ctx is cancelled the
+// partial file is removed, unless keepPartial is set or the transfer was
+// paused.
+func downloadFile(ctx context.Context, clientManager *ClientManager, bucketName, key, localPath string, keepPartial bool, onProgress func(current, total int64)) error {
 	// Get region-specific client for this bucket
-	bucketClient, err := clientManager.GetClientForBucket(context.TODO(), bucketName)
+	client, err := clientManager.GetClientForBucket(ctx, bucketName)
 	if err != nil {
 		return fmt.Errorf("failed to get client for bucket: %w", err)
 	}
 
-	// Convert to concrete client for direct S3 operations
-	var client *s3.Client
-	if concreteClient, ok := bucketClient.(*s3.Client); ok {
-		client = concreteClient
-	} else {
-		return fmt.Errorf("failed to get concrete S3 client")
-	}
-
 	// Get the object from S3
-	resp, err := client.GetObject(context.TODO(), &s3.GetObjectInput{
+	resp, err := client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: &bucketName,
 		Key:    &key,
 	})
@@ -151,18 +165,15 @@ func downloadFile(clientManager *ClientManager, bucketName, key string, onProgre
 	}
 	defer resp.Body.Close()
 
-	// Extract filename from key (get the last part after the last slash)
-	filename := filepath.Base(key)
-	if filename == "." || filename == "/" {
-		filename = "downloaded_file"
-	}
-
 	// Create the local file
-	file, err := os.Create(filename)
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create local directory: %w", err)
+	}
+	partialPath := localPath + partialSuffix
+	file, err := os.Create(partialPath)
 	if err != nil {
 		return fmt.Errorf("failed to create local file: %w", err)
 	}
-	defer file.Close()
 
 	// Get content length for progress tracking
 	contentLength := int64(0)
@@ -172,32 +183,87 @@ func downloadFile(clientManager *ClientManager, bucketName, key string, onProgre
 
 	// Create progress reader
 	progressReader := &ProgressReader{
-		reader:     resp.Body,
+		reader:     &contextReader{ctx: ctx, reader: resp.Body},
 		total:      contentLength,
 		onProgress: onProgress,
 	}
 
 	// Copy the content with progress tracking
 	_, err = io.Copy(file, progressReader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
+		if !keepPartial && !transferPaused(ctx) {
+			os.Remove(partialPath)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("failed to write file content: %w", err)
 	}
 
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return fmt.Errorf("failed to move downloaded file into place: %w", err)
+	}
+
 	return nil
 }
 
 // AppState holds the current state of the application
 type AppState struct {
-	CurrentBucket string `json:"current_bucket"`
-	CurrentPrefix string `json:"current_prefix"`
+	CurrentBucket   string          `json:"current_bucket"`
+	CurrentPrefix   string          `json:"current_prefix"`
+	LastDownloadDir string          `json:"last_download_dir"`
+	CollisionPolicy collisionPolicy `json:"collision_policy"`
+	KeepPartial     bool            `json:"keep_partial"`
+
+	TransferConcurrency int `json:"transfer_concurrency"`
+
+	SortField      sortField `json:"sort_field"`
+	SortDescending bool      `json:"sort_descending"`
+
+	// Whether folder sizes are computed as soon as folders are listed
+	AutoDirSizes bool `json:"auto_dir_sizes"`
+
+	// The selected entry and the object open in the viewer, if any, with
+	// the viewer's scroll offset. The selection is saved on exit.
+	SelectedKey  string `json:"selected_key"`
+	ViewerKey    string `json:"viewer_key"`
+	ViewerOffset int    `json:"viewer_offset"`
+
+	// The open tabs, if there are several, and which one is active. The
+	// active tab is at CurrentBucket and CurrentPrefix.
+	Tabs      []TabState `json:"tabs,omitempty"`
+	ActiveTab int        `json:"active_tab"`
 }
 
+// paginationLookahead is how many rows before the end of the loaded entries
+// the selection may get before the next listing page is requested
+const paginationLookahead = 50
+
 // ObjectEntry holds information about an S3 object for display
 type ObjectEntry struct {
 	Key          string
 	IsDirectory  bool
 	Size         int64
 	LastModified *time.Time
+	ETag         string
+	StorageClass string
+}
+
+// paneState records what a pane of the browser shows: an S3 listing, the
+// bucket list (an empty bucket) or the local filesystem
+type paneState struct {
+	view       tview.Primitive
+	bucket     string
+	prefix     string
+	objectFlex *tview.Flex
+	local      *localPane
+	refresh    func()
+	selected   func() []ObjectEntry
+	stop       func() // stops the listing's background work once it is left
+	history    *navHistory
 }
 
 // getConfigPath returns the path to the config file
@@ -410,12 +476,12 @@ func decompressIfGzipped(data []byte, filename string) ([]byte, error) {
 
 func main() {
 	// Parse command line arguments
+	transfers := flag.Int("transfers", 0, fmt.Sprintf("number of transfers to run at once (default: last used, or %d)", defaultTransferConcurrency))
+	bookmarkName := flag.String("bookmark", "", "start at the bookmark with this name")
 	flag.Parse()
 
-	// Global variables to track current view state for help dialog
-	var currentBucketName string
+	// The object view of the active pane, if it shows a listing
 	var currentObjectFlex *tview.Flex
-	var currentMainFlex *tview.Flex
 
 	var s3URL string
 	if len(flag.Args()) > 0 {
@@ -451,8 +517,51 @@ func main() {
 		currentState.CurrentPrefix = prefix
 	}
 
+	// Bookmarks are kept in their own file next to the state. If it cannot
+	// be read, it is left alone rather than overwritten with the bookmarks
+	// of this session.
+	bookmarks, bookmarksErr := loadBookmarks()
+	if bookmarksErr != nil {
+		log.Printf("failed to load bookmarks: %v", bookmarksErr)
+	}
+	storeBookmarks := func() error {
+		if bookmarksErr != nil {
+			return fmt.Errorf("not overwriting the bookmarks file, which could not be read: %w", bookmarksErr)
+		}
+		return saveBookmarks(bookmarks)
+	}
+	if *bookmarkName != "" {
+		if s3URL != "" {
+			log.Fatalf("use either an S3 URL or -bookmark, not both")
+		}
+		i := findBookmark(bookmarks, *bookmarkName)
+		if i < 0 {
+			log.Fatalf("unknown bookmark %q", *bookmarkName)
+		}
+		currentState.CurrentBucket = bookmarks[i].Bucket
+		currentState.CurrentPrefix = bookmarks[i].Prefix
+	}
+
 	// Create TUI application
 	app := tview.NewApplication()
+
+	// Transfers run in a background queue so browsing continues while they do
+	concurrency := currentState.TransferConcurrency
+	if *transfers > 0 {
+		concurrency = *transfers
+	}
+	if concurrency < 1 {
+		concurrency = defaultTransferConcurrency
+	}
+	queue := NewTransferQueue(concurrency)
+
+	// Entries yanked for copying or moving, pasted with 'p' in any listing
+	var yanked *yankedEntries
+
+	// Sizes of the folders computed in any listing, by usageKey. Only
+	// touched on the UI goroutine.
+	dirUsages := make(map[string]dirUsage)
+
 	bucketTable := tview.NewTable().
 		SetBorders(false).
 		SetSelectable(true, false)
@@ -460,61 +569,118 @@ func main() {
 		SetTextAlign(tview.AlignCenter).
 		SetText("Select an S3 bucket")
 
-	// Store bucket entries for proper navigation
+	// Store bucket entries for proper navigation. bucketEntries holds the
+	// buckets shown, which are those of allBuckets that pass bucketFilter.
+	var allBuckets []types.Bucket
 	var bucketEntries []types.Bucket
+	var bucketFilter *entryFilter
+	bucketRegions := make(map[string]string)
+
+	// Locations visited by the active pane, for going back and forward.
+	// Each pane has its own; the bucket list is where every session starts.
+	history := newNavHistory()
+	history.visit("", "")
 
 	// Global variable to store the current refresh function for resize handling
 	var currentRefreshFunc func()
 
-	// Fetch S3 buckets and populate the table
-	go func() {
-		buckets, err := getBuckets(context.TODO(), client)
-		if err != nil {
-			log.Fatalf("failed to list buckets: %v", err)
+	// bucketTitle returns the bucket table title, with the filter if one is set
+	bucketTitle := func() string {
+		if bucketFilter != nil {
+			return fmt.Sprintf(" Buckets [%s filter %q: %d of %d] (Press '/' to change, ESC to clear) ", bucketFilter.mode, bucketFilter.pattern, len(bucketEntries), len(allBuckets))
 		}
+		return " Buckets (Press '/' to filter) "
+	}
+	bucketTable.SetBorder(true).SetTitle(bucketTitle())
 
-		app.QueueUpdateDraw(func() {
-			// Clear and set up table headers
-			bucketTable.Clear()
-			bucketTable.SetCell(0, 0, tview.NewTableCell("Bucket Name").SetTextColor(tcell.ColorYellow).SetSelectable(false))
-			bucketTable.SetCell(0, 1, tview.NewTableCell("Region").SetTextColor(tcell.ColorYellow).SetSelectable(false))
-			bucketTable.SetCell(0, 2, tview.NewTableCell("Created").SetTextColor(tcell.ColorYellow).SetSelectable(false))
-
-			bucketEntries = buckets
-			row := 1
-			for _, bucket := range buckets {
-				bucketName := *bucket.Name
-				creationDate := ""
-				if bucket.CreationDate != nil {
-					creationDate = bucket.CreationDate.Format("2006-01-02 15:04")
-				}
+	// setBucketRegion renders the region of a bucket if it is shown
+	setBucketRegion := func(bucketName string) {
+		for i, bucket := range bucketEntries {
+			if *bucket.Name != bucketName {
+				continue
+			}
+			if region, ok := bucketRegions[bucketName]; ok {
+				bucketTable.SetCell(i+1, 1, tview.NewTableCell(region))
+			} else {
+				bucketTable.SetCell(i+1, 1, tview.NewTableCell("Loading...").SetTextColor(tcell.ColorGray))
+			}
+			return
+		}
+	}
 
-				bucketTable.SetCell(row, 0, tview.NewTableCell(bucketName))
-				bucketTable.SetCell(row, 1, tview.NewTableCell("Loading...").SetTextColor(tcell.ColorGray))
-				bucketTable.SetCell(row, 2, tview.NewTableCell(creationDate))
+	// renderBuckets fills the table with the buckets passing the filter,
+	// keeping the selected bucket selected if it is still shown
+	renderBuckets := func() {
+		selected := ""
+		if row, _ := bucketTable.GetSelection(); row > 0 && row-1 < len(bucketEntries) {
+			selected = *bucketEntries[row-1].Name
+		}
 
-				// Fetch region for this bucket asynchronously
-				go func(bucketName string, rowIndex int) {
-					region, err := getBucketRegion(context.TODO(), client, bucketName)
-					regionText := region
-					if err != nil {
-						regionText = "Error"
-					}
+		// Clear and set up table headers
+		bucketTable.Clear()
+		bucketTable.SetCell(0, 0, tview.NewTableCell("Bucket Name").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		bucketTable.SetCell(0, 1, tview.NewTableCell("Region").SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		bucketTable.SetCell(0, 2, tview.NewTableCell("Created").SetTextColor(tcell.ColorYellow).SetSelectable(false))
 
-					app.QueueUpdateDraw(func() {
-						bucketTable.SetCell(rowIndex, 1, tview.NewTableCell(regionText))
-					})
-				}(bucketName, row)
+		bucketEntries = nil
+		for _, bucket := range allBuckets {
+			if bucketFilter.matches(*bucket.Name) {
+				bucketEntries = append(bucketEntries, bucket)
+			}
+		}
 
-				row++
+		selectedRow := 1
+		for i, bucket := range bucketEntries {
+			row := i + 1
+			bucketName := *bucket.Name
+			creationDate := ""
+			if bucket.CreationDate != nil {
+				creationDate = bucket.CreationDate.Format("2006-01-02 15:04")
 			}
 
-			// Select first bucket if available
-			if len(buckets) > 0 {
-				bucketTable.Select(1, 0)
-				text.SetText(fmt.Sprintf("s3://%s", *buckets[0].Name))
+			bucketTable.SetCell(row, 0, tview.NewTableCell(bucketName))
+			bucketTable.SetCell(row, 2, tview.NewTableCell(creationDate))
+			setBucketRegion(bucketName)
+			if bucketName == selected {
+				selectedRow = row
 			}
+		}
+		bucketTable.SetTitle(bucketTitle())
+
+		// Select the first bucket if the previous one is no longer shown
+		if len(bucketEntries) > 0 {
+			bucketTable.Select(selectedRow, 0)
+			text.SetText(fmt.Sprintf("s3://%s", *bucketEntries[selectedRow-1].Name))
+		}
+	}
+
+	// Fetch S3 buckets and populate the table
+	go func() {
+		buckets, err := getBuckets(context.TODO(), client)
+		if err != nil {
+			log.Fatalf("failed to list buckets: %v", err)
+		}
+
+		app.QueueUpdateDraw(func() {
+			allBuckets = buckets
+			renderBuckets()
 		})
+
+		// Fetch regions asynchronously
+		for _, bucket := range buckets {
+			go func(bucketName string) {
+				region, err := getBucketRegion(context.TODO(), client, bucketName)
+				regionText := region
+				if err != nil {
+					regionText = "Error"
+				}
+
+				app.QueueUpdateDraw(func() {
+					bucketRegions[bucketName] = regionText
+					setBucketRegion(bucketName)
+				})
+			}(*bucket.Name)
+		}
 	}()
 
 	// Update path display when bucket selection changes
@@ -522,6 +688,7 @@ func main() {
 		if row > 0 && row-1 < len(bucketEntries) { // Skip header row
 			bucketName := *bucketEntries[row-1].Name
 			text.SetText(fmt.Sprintf("s3://%s", bucketName))
+			history.setSelected("", "", bucketName)
 		}
 	})
 
@@ -531,219 +698,1364 @@ func main() {
 		AddItem(text, 3, 1, false).
 		AddItem(bucketTable, 0, 1, true)
 
-	// Store reference for help dialog
-	currentMainFlex = flex
+	// The main layout shows the current view above the optional transfers
+	// panel and a status line
+	content := tview.NewFlex().SetDirection(tview.FlexRow)
+	statusBar := tview.NewTextView().SetDynamicColors(true)
+	tabBar := tview.NewTextView().SetDynamicColors(true)
+	mainLayout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(tabBar, 0, 0, false).
+		AddItem(content, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+
+	// The dual-pane layout, toggled with '|', shows two panes side by side,
+	// each in its own slot. In the single layout only the active pane is
+	// shown.
+	var panes [2]paneState
+	var activePane int
+	var dualPane bool
+	paneSlots := [2]*tview.Flex{tview.NewFlex(), tview.NewFlex()}
+	panesFlex := tview.NewFlex()
+
+	panes[0].history = history
+
+	// Each tab has its own panes. Those of the current tab are the variables
+	// above; tabs keeps the others.
+	tabs := []*browserTab{{}}
+	currentTab := 0
+
+	// storeTab keeps the panes of the current tab in tabs
+	storeTab := func() {
+		tabs[currentTab] = &browserTab{
+			panes:      panes,
+			activePane: activePane,
+			dualPane:   dualPane,
+		}
+	}
 
-	var showFileContent func(bucketName, objectKey string, previousFlex *tview.Flex)
+	// renderTabs shows the tab bar if there are several tabs, and updates
+	// the tabs to save
+	renderTabs := func() {
+		storeTab()
+		if len(tabs) < 2 {
+			currentState.Tabs = nil
+			currentState.ActiveTab = 0
+			mainLayout.ResizeItem(tabBar, 0, 0)
+			return
+		}
 
-	// Function to list objects in a bucket
-	var listObjects func(bucketName, prefix string)
-	showFileContent = func(bucketName, objectKey string, previousFlex *tview.Flex) {
-		// Update current state
-		currentState.CurrentBucket = bucketName
-		currentState.CurrentPrefix = strings.TrimSuffix(objectKey, filepath.Base(objectKey))
-		saveState(currentState)
+		labels := make([]string, len(tabs))
+		currentState.Tabs = make([]TabState, len(tabs))
+		for i, tab := range tabs {
+			labels[i] = tab.label()
+			currentState.Tabs[i] = tab.state()
+		}
+		currentState.ActiveTab = currentTab
+		tabBar.SetText(formatTabBar(labels, currentTab))
+		mainLayout.ResizeItem(tabBar, 1, 0)
+	}
 
-		// Determine if this might be an image file for better loading message
-		loadingMessage := "Loading file content..."
-		if isImageFile(objectKey) {
-			loadingMessage = "Loading image and converting to ASCII art..."
+	// showView displays p as the current view of the active pane
+	showView := func(p tview.Primitive) {
+		panes[activePane].view = p
+		content.Clear()
+		if dualPane {
+			paneSlots[activePane].Clear()
+			paneSlots[activePane].AddItem(p, 0, 1, true)
+			content.AddItem(panesFlex, 0, 1, true)
+		} else {
+			content.AddItem(p, 0, 1, true)
+		}
+		app.SetRoot(mainLayout, true)
+		app.SetFocus(p)
+		renderTabs()
+	}
+	panes[0].view = flex
+	content.AddItem(flex, 0, 1, true)
+
+	// layoutPanes fills the content with the panes of the current layout
+	layoutPanes := func() {
+		content.Clear()
+		if !dualPane {
+			content.AddItem(panes[activePane].view, 0, 1, true)
+			return
+		}
+		panesFlex.Clear()
+		for i, slot := range paneSlots {
+			slot.Clear()
+			if panes[i].view != nil {
+				slot.AddItem(panes[i].view, 0, 1, true)
+			}
+			panesFlex.AddItem(slot, 0, 1, i == activePane)
+		}
+		content.AddItem(panesFlex, 0, 1, true)
+	}
+
+	// activatePane makes pane i the one receiving keys and actions
+	activatePane := func(i int) {
+		activePane = i
+		pane := panes[i]
+		history = pane.history
+		currentObjectFlex = pane.objectFlex
+		if pane.refresh != nil {
+			currentRefreshFunc = pane.refresh
+		}
+		if pane.local == nil && pane.bucket != "" {
+			currentState.CurrentBucket = pane.bucket
+			currentState.CurrentPrefix = pane.prefix
+			currentState.SelectedKey = ""
+			if loc, ok := history.current(); ok && loc.bucket == pane.bucket && loc.prefix == pane.prefix {
+				currentState.SelectedKey = loc.selected
+			}
 		}
+		layoutPanes()
+		app.SetFocus(pane.view)
+		renderTabs()
+	}
 
-		textView := tview.NewTextView().
-			SetText(loadingMessage).
-			SetDynamicColors(true)
+	// setPane records what the active pane shows from now on, stopping the
+	// background work of the listing it showed before. The pane keeps its
+	// history.
+	setPane := func(pane paneState) {
+		if stop := panes[activePane].stop; stop != nil {
+			stop()
+		}
+		pane.history = panes[activePane].history
+		panes[activePane] = pane
+	}
 
-		textView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-			if event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyLeft {
-				app.SetRoot(previousFlex, true)
-				return nil
+	// refreshListing reloads the shown panes listing bucket and prefix, e.g.
+	// after a transfer into it finished
+	refreshListing := func(bucket, prefix string) {
+		for i, pane := range panes {
+			if (dualPane || i == activePane) && pane.local == nil && pane.bucket == bucket && pane.prefix == prefix && pane.refresh != nil {
+				pane.refresh()
 			}
-			if event.Key() == tcell.KeyRune && event.Rune() == ' ' {
-				// Scroll down a page
-				row, col := textView.GetScrollOffset()
-				_, _, _, height := textView.GetRect()
-				textView.ScrollTo(row+height-1, col)
-				return nil
+		}
+	}
+
+	// refreshLocalDir reloads the shown panes browsing the local directory dir
+	refreshLocalDir := func(dir string) {
+		for i, pane := range panes {
+			if (dualPane || i == activePane) && pane.local != nil && pane.local.dir == dir {
+				pane.local.refresh()
 			}
-			return event
+		}
+	}
+
+	// otherLocalDir returns the directory shown in the inactive pane, if it
+	// browses the local filesystem, as the default for downloads and uploads
+	otherLocalDir := func() (string, bool) {
+		if other := panes[1-activePane]; dualPane && other.local != nil {
+			return other.local.dir, true
+		}
+		return "", false
+	}
+
+	// Status messages from finished transfers are shown in the status line
+	// for a few seconds, in place of the transfers summary
+	var statusMu sync.Mutex
+	var statusMessage string
+	var statusExpires time.Time
+	notify := func(message string) {
+		statusMu.Lock()
+		defer statusMu.Unlock()
+		statusMessage = message
+		statusExpires = time.Now().Add(5 * time.Second)
+	}
+	statusText := func(infos []transferInfo) string {
+		statusMu.Lock()
+		defer statusMu.Unlock()
+		if statusMessage != "" && time.Now().Before(statusExpires) {
+			return " " + tview.Escape(statusMessage)
+		}
+		if summary := summarizeTransfers(infos); summary != "" {
+			return " " + summary + " [gray](press 't' for details)[-]"
+		}
+		return ""
+	}
+
+	// Transfers panel, toggled with 't'
+	var transfersPanel *tview.Table
+	var refreshTransfersPanel func(infos []transferInfo)
+	var transfersPanelVisible bool
+	var refreshTransferDetail func()
+
+	hideTransfersPanel := func() {
+		mainLayout.RemoveItem(transfersPanel)
+		transfersPanelVisible = false
+		app.SetFocus(content)
+	}
+	transfersPanel, refreshTransfersPanel = newTransfersPanel(queue, func(concurrency int) {
+		currentState.TransferConcurrency = concurrency
+		saveState(currentState)
+	}, func(id int) {
+		detail, refresh := showTransferDetail(queue, id, func() {
+			refreshTransferDetail = nil
+			app.SetRoot(mainLayout, true)
+			app.SetFocus(transfersPanel)
 		})
+		refreshTransferDetail = refresh
+		app.SetRoot(detail, true)
+	}, func() {
+		app.SetFocus(content)
+	})
 
-		go func() {
-			// Get region-specific client for this bucket
-			bucketClient, err := clientManager.GetClientForBucket(context.TODO(), bucketName)
-			if err != nil {
-				app.QueueUpdateDraw(func() {
-					textView.SetText(fmt.Sprintf("Error getting client: %v", err))
-				})
-				return
-			}
+	// toggleTransfersPanel shows and focuses the transfers panel, or hides it
+	// if it already has focus
+	toggleTransfersPanel := func() {
+		switch {
+		case !transfersPanelVisible:
+			mainLayout.RemoveItem(statusBar)
+			mainLayout.AddItem(transfersPanel, 10, 0, false)
+			mainLayout.AddItem(statusBar, 1, 0, false)
+			transfersPanelVisible = true
+			refreshTransfersPanel(queue.Jobs())
+			app.SetFocus(transfersPanel)
+		case transfersPanel.HasFocus():
+			hideTransfersPanel()
+		default:
+			app.SetFocus(transfersPanel)
+		}
+	}
 
-			body, err := getObjectContent(context.TODO(), bucketClient, bucketName, objectKey)
-			if err != nil {
-				app.QueueUpdateDraw(func() {
-					textView.SetText(fmt.Sprintf("Error: %v", err))
-				})
-				return
+	// Redraw the transfers panel and status line while transfers are active,
+	// and whenever the summary changes
+	go func() {
+		var lastStatus string
+		for range time.Tick(transfersRefreshInterval) {
+			infos := queue.Jobs()
+			status := statusText(infos)
+			active := false
+			for _, info := range infos {
+				if info.State == transferRunning {
+					active = true
+					break
+				}
 			}
-
-			// Decompress if gzipped
-			decompressed, err := decompressIfGzipped(body, objectKey)
-			if err != nil {
-				app.QueueUpdateDraw(func() {
-					textView.SetText(fmt.Sprintf("Error decompressing: %v", err))
-				})
-				return
+			if !active && status == lastStatus {
+				continue
 			}
+			lastStatus = status
 
 			app.QueueUpdateDraw(func() {
-				// Get current terminal dimensions for optimal ASCII art scaling
-				_, _, width, height := textView.GetRect()
-				if width == 0 {
-					width = getTerminalWidth()
+				statusBar.SetText(status)
+				if transfersPanelVisible {
+					refreshTransfersPanel(infos)
 				}
-				if height == 0 {
-					height = getTerminalHeight()
+				if refreshTransferDetail != nil {
+					refreshTransferDetail()
 				}
+			})
+		}
+	}()
 
-				// Try to convert to ASCII art if it's an image
-				if ascii, isImage := convertToASCIIArt(decompressed, objectKey, width, height); isImage {
-					textView.SetText("[green]ASCII Art Preview[white]\n\n" + ascii + "\n\n[yellow]Press ESC or Left Arrow to go back[white]")
-				} else {
-					// Display as regular text
-					content := string(decompressed)
-					if len(content) > 0 {
-						textView.SetText(content)
-					} else {
-						textView.SetText("[yellow]File is empty or contains binary data[white]")
+	// queueDownloads queues one download per entry, so each can be paused,
+	// retried or cancelled on its own. Only downloads of a single large
+	// object resume where they were paused; the others start over.
+	queueDownloads := func(bucketName, prefix string, entries []ObjectEntry, destDir string, options downloadOptions) {
+		for _, entry := range entries {
+			entry := entry
+			name := entryBaseName(entry.Key)
+			run := func(ctx context.Context, report func(current, total int64, detail string)) error {
+				bucketClient, err := clientManager.GetClientForBucket(ctx, bucketName)
+				if err != nil {
+					return err
+				}
+				targets, err := planDownloads(ctx, bucketClient, bucketName, prefix, []ObjectEntry{entry}, destDir)
+				if err != nil {
+					return err
+				}
+				_, err = downloadTargets(ctx, clientManager, bucketName, targets, options, func(filesDone, filesTotal int, current, total int64) {
+					detail := ""
+					if filesTotal > 1 {
+						detail = fmt.Sprintf("%d of %d files", filesDone, filesTotal)
 					}
+					report(current, total, detail)
+				})
+				return err
+			}
+			resumable := !entry.IsDirectory && entry.Size >= rangedDownloadThreshold
+			queue.Add("Download", name, resumable, run, func(info transferInfo) {
+				switch info.State {
+				case transferDone:
+					notify(fmt.Sprintf("Downloaded %s to %s", name, destDir))
+				case transferFailed:
+					notify(fmt.Sprintf("Download of %s failed: %v", name, info.Err))
+				default:
+					return
 				}
+				app.QueueUpdateDraw(func() {
+					refreshLocalDir(destDir)
+				})
 			})
-		}()
-
-		app.SetRoot(textView, true)
+		}
 	}
-	listObjects = func(bucketName, prefix string) {
-		// Update current state
-		currentState.CurrentBucket = bucketName
-		currentState.CurrentPrefix = prefix
-		saveState(currentState)
-
-		// Update global variables for help dialog
-		currentBucketName = bucketName
-
-		currentPath := fmt.Sprintf("s3://%s/%s", bucketName, prefix)
-		text.SetText(currentPath)
-		// Create table with proper columns
-		objectTable := tview.NewTable().
-			SetBorders(false).
-			SetSelectable(true, false)
-
-		// Store object entries for proper key handling
-		var objectEntries []ObjectEntry
-
-		objectFlex := tview.NewFlex().
-			SetDirection(tview.FlexRow).
-			AddItem(text, 3, 1, false).
-			AddItem(objectTable, 0, 1, true)
 
-		// Store reference for help dialog
-		currentObjectFlex = objectFlex
-
-		objectTable.SetSelectedFunc(func(row, column int) {
-			if row > 0 && row-1 < len(objectEntries) { // Skip header row
-				entry := objectEntries[row-1]
-				if entry.IsDirectory {
-					listObjects(bucketName, entry.Key)
-				} else {
-					showFileContent(bucketName, entry.Key, objectFlex)
-				}
+	// queueUpload queues the upload of a local file or directory into prefix
+	queueUpload := func(localPath, bucketName, prefix string) {
+		uploadName := filepath.Base(localPath)
+		run := func(ctx context.Context, report func(current, total int64, detail string)) error {
+			bucketClient, err := clientManager.GetClientForBucket(ctx, bucketName)
+			if err != nil {
+				return err
 			}
-		})
-
-		// Update path display when selection changes
-		objectTable.SetSelectionChangedFunc(func(row, column int) {
-			if row > 0 && row-1 < len(objectEntries) { // Skip header row
-				filename := objectEntries[row-1].Key
-				path := fmt.Sprintf("s3://%s/%s", bucketName, filename)
-				text.SetText(path)
+			_, err = uploadPath(ctx, bucketClient, bucketName, prefix, localPath, func(current, total int64) {
+				report(current, total, "")
+			})
+			return err
+		}
+		queue.Add("Upload", uploadName, false, run, func(info transferInfo) {
+			switch info.State {
+			case transferDone:
+				notify(fmt.Sprintf("Uploaded %s to s3://%s/%s", uploadName, bucketName, prefix))
+			case transferFailed:
+				notify(fmt.Sprintf("Upload of %s failed: %v", uploadName, info.Err))
+			default:
+				return
 			}
+
+			// Show the new objects if their prefix is still being browsed
+			app.QueueUpdateDraw(func() {
+				refreshListing(bucketName, prefix)
+			})
 		})
+	}
 
-		// Function to populate the table with current data
-		populateObjectTable := func() {
-			objectTable.Clear()
-			objectEntries = nil // Reset entries
+	// pasteEntries copies or moves the source entries into destPrefix of
+	// destBucket, queueing one transfer per object. Progress messages go to
+	// flash; onQueued is called once the transfers are queued.
+	pasteEntries := func(source yankedEntries, destBucket, destPrefix string, flash func(message string, duration time.Duration), onQueued func()) {
+		go func() {
+			var targets []copyTarget
+			srcClient, err := clientManager.GetClientForBucket(context.TODO(), source.bucket)
+			if err == nil {
+				targets, err = planCopies(context.TODO(), srcClient, source.bucket, source.prefix, source.entries, destBucket, destPrefix)
+			}
 
-			go func() {
-				// Get region-specific client for this bucket
-				bucketClient, err := clientManager.GetClientForBucket(context.TODO(), bucketName)
+			app.QueueUpdateDraw(func() {
 				if err != nil {
-					log.Printf("failed to get client for bucket: %v", err)
+					flash(fmt.Sprintf("Paste failed: %v", err), 3*time.Second)
 					return
 				}
-
-				objects, err := listS3Objects(context.TODO(), bucketClient, bucketName, prefix)
-				if err != nil {
-					log.Printf("failed to list objects: %v", err)
+				if len(targets) == 0 {
+					flash("Nothing to paste, the yanked entries are empty", 2*time.Second)
 					return
 				}
-
-				app.QueueUpdateDraw(func() {
-					// Add table headers
-					objectTable.SetCell(0, 0, tview.NewTableCell("Name").SetTextColor(tcell.ColorYellow).SetSelectable(false))
-					objectTable.SetCell(0, 1, tview.NewTableCell("Size").SetTextColor(tcell.ColorYellow).SetSelectable(false))
-					objectTable.SetCell(0, 2, tview.NewTableCell("Modified").SetTextColor(tcell.ColorYellow).SetSelectable(false))
-
-					row := 1
-
-					// Add directories first
-					for _, p := range objects.CommonPrefixes {
-						entry := ObjectEntry{
-							Key:         *p.Prefix,
-							IsDirectory: true,
+				action, verb := "Copy", "Copied"
+				if source.move {
+					action, verb = "Move", "Moved"
+				}
+				destination := fmt.Sprintf("s3://%s/%s", destBucket, destPrefix)
+
+				// Queue one transfer per object so progress and failures
+				// are reported for each; refresh once all have finished
+				batch := newTransferBatch(len(targets))
+				for i, target := range targets {
+					i, target := i, target
+					name := strings.TrimPrefix(target.srcKey, source.prefix)
+					run := func(ctx context.Context, report func(current, total int64, detail string)) error {
+						return transferS3Object(ctx, clientManager, source.bucket, destBucket, target, source.move, func(current, total int64) {
+							report(current, total, "")
+						})
+					}
+					queue.Add(action, name, false, run, func(info transferInfo) {
+						if !info.State.finished() {
+							return
+						}
+						if info.State == transferFailed {
+							notify(fmt.Sprintf("%s of %s failed: %v", action, name, info.Err))
 						}
-						objectEntries = append(objectEntries, entry)
-						objectTable.SetCell(row, 0, tview.NewTableCell(*p.Prefix).SetTextColor(tcell.ColorBlue))
-						objectTable.SetCell(row, 1, tview.NewTableCell("DIR").SetTextColor(tcell.ColorBlue))
-						objectTable.SetCell(row, 2, tview.NewTableCell("").SetTextColor(tcell.ColorBlue))
-						row++
-					}
-
-					// Add files
-					for _, o := range objects.Contents {
-						if *o.Key != prefix {
-							entry := ObjectEntry{
-								Key:          *o.Key,
-								IsDirectory:  false,
-								Size:         *o.Size,
-								LastModified: o.LastModified,
-							}
-							objectEntries = append(objectEntries, entry)
-
-							sizeStr := formatFileSize(*o.Size)
-							dateStr := formatDate(o.LastModified)
 
-							objectTable.SetCell(row, 0, tview.NewTableCell(*o.Key))
-							objectTable.SetCell(row, 1, tview.NewTableCell(sizeStr))
-							objectTable.SetCell(row, 2, tview.NewTableCell(dateStr))
-							row++
+						done, failed, cancelled := batch.finish(i, info.State)
+						if !done {
+							return
 						}
-					}
+
+						switch {
+						case failed > 0 && cancelled > 0:
+							notify(fmt.Sprintf("%d of %d object(s) failed and %d were cancelled, press 't' for details", failed, len(targets), cancelled))
+						case failed > 0:
+							notify(fmt.Sprintf("%d of %d object(s) failed, press 't' for details", failed, len(targets)))
+						case cancelled > 0:
+							notify(fmt.Sprintf("%s %d of %d object(s) to %s, %d cancelled", verb, len(targets)-cancelled, len(targets), destination, cancelled))
+						default:
+							notify(fmt.Sprintf("%s %d object(s) to %s", verb, len(targets), destination))
+						}
+						app.QueueUpdateDraw(func() {
+							refreshListing(destBucket, destPrefix)
+							if source.move {
+								refreshListing(source.bucket, source.prefix)
+							}
+						})
+					})
+				}
+				onQueued()
+				flash(fmt.Sprintf("Queued %d object(s) to %s, press 't' for transfers", len(targets), strings.ToLower(action)), 3*time.Second)
+			})
+		}()
+	}
+
+	// showFileContent shows an object in the viewer, scrolled down to
+	// scrollRow once loaded
+	var showFileContent func(bucketName, objectKey string, previousFlex *tview.Flex, scrollRow int)
+
+	// viewerScroll returns the scroll offset of the open viewer, or is nil if
+	// the viewer is closed. It is saved on exit.
+	var viewerScroll func() int
+
+	// showBuckets returns to the bucket list, selecting the named bucket if
+	// it is shown
+	showBuckets := func(selectBucket string) {
+		history.visit("", "")
+		setPane(paneState{})
+		currentObjectFlex = nil
+		for i, bucket := range bucketEntries {
+			if *bucket.Name == selectBucket {
+				bucketTable.Select(i+1, 0)
+			}
+		}
+		showView(flex)
+	}
+
+	// Function to list objects in a bucket, selecting selectKey once it has
+	// been loaded
+	var listObjects func(bucketName, prefix, selectKey string)
+
+	// showGoTo opens the go-to prompt at the given location; back returns to
+	// the view it was opened from
+	var showGoTo func(bucketName, prefix string, back func())
+
+	// showLocal browses the local directory dir in the active pane. 'L'
+	// returns to the last S3 location.
+	showLocal := func(dir string) {
+		local := newLocalPane(dir, func() {
+			if loc, ok := history.current(); ok && loc.bucket != "" {
+				listObjects(loc.bucket, loc.prefix, loc.selected)
+			} else {
+				showBuckets("")
+			}
+		})
+		setPane(paneState{local: local, refresh: local.refresh})
+		currentObjectFlex = nil
+		currentRefreshFunc = local.refresh
+		showView(local.flex)
+	}
+
+	// toggleDualPane switches between the single and the dual-pane layout.
+	// The second pane starts out in the local filesystem, with a history of
+	// its own.
+	toggleDualPane := func() {
+		dualPane = !dualPane
+		if other := 1 - activePane; dualPane && panes[other].view == nil {
+			panes[other].history = newNavHistory()
+			dir := currentState.LastDownloadDir
+			if dir == "" {
+				dir, _ = os.Getwd()
+			}
+			active := activePane
+			activePane = other
+			showLocal(dir)
+			activePane = active
+		}
+		activatePane(activePane)
+	}
+
+	// transferToOtherPane copies (or moves) the selection of the active pane
+	// into the location of the other one: between S3 locations server-side,
+	// and to or from the local filesystem by downloading or uploading
+	transferToOtherPane := func(move bool) {
+		from, to := panes[activePane], panes[1-activePane]
+		switch {
+		case from.local != nil && to.local != nil:
+			notify("Both panes show the local filesystem")
+		case move && (from.local != nil || to.local != nil):
+			notify("Only copying (F5) is supported to and from the local filesystem")
+		case from.local != nil:
+			if to.bucket == "" {
+				notify("Open a bucket in the other pane first")
+				return
+			}
+			paths := from.local.selectedPaths()
+			for _, path := range paths {
+				queueUpload(path, to.bucket, to.prefix)
+			}
+			notify(fmt.Sprintf("Queued %d upload(s) to s3://%s/%s", len(paths), to.bucket, to.prefix))
+		case from.selected == nil || len(from.selected()) == 0:
+			notify("Select entries in a folder to copy or move them")
+		case to.local != nil:
+			entries := from.selected()
+			options := downloadOptions{
+				policy:      currentState.CollisionPolicy,
+				keepPartial: currentState.KeepPartial,
+			}
+			queueDownloads(from.bucket, from.prefix, entries, to.local.dir, options)
+			notify(fmt.Sprintf("Queued %d download(s) to %s", len(entries), to.local.dir))
+		case to.bucket == "":
+			notify("Open a bucket in the other pane first")
+		default:
+			source := yankedEntries{
+				bucket:  from.bucket,
+				prefix:  from.prefix,
+				entries: from.selected(),
+				move:    move,
+			}
+			pasteEntries(source, to.bucket, to.prefix, func(message string, duration time.Duration) {
+				notify(message)
+			}, func() {})
+		}
+	}
+
+	// loadTab makes tab i the current tab, opening its location if it has
+	// not been shown yet
+	loadTab := func(i int) {
+		currentTab = i
+		tab := tabs[i]
+		panes, activePane, dualPane = tab.panes, tab.activePane, tab.dualPane
+		history = panes[activePane].history
+
+		// The viewer of the tab left behind is not restored on the next start
+		viewerScroll = nil
+		currentState.ViewerKey = ""
+		currentState.ViewerOffset = 0
+
+		if !tab.shown() {
+			state := tab.state()
+			if state.Bucket == "" {
+				showBuckets(state.SelectedKey)
+			} else {
+				listObjects(state.Bucket, state.Prefix, state.SelectedKey)
+			}
+			return
+		}
+		activatePane(activePane)
+		if state := tab.state(); state.Bucket != "" {
+			currentState.SelectedKey = state.SelectedKey
+		}
+		saveState(currentState)
+	}
+
+	// switchTab moves to tab i, keeping the current one as it is
+	switchTab := func(i int) {
+		storeTab()
+		loadTab((i + len(tabs)) % len(tabs))
+	}
+
+	// openTab opens a tab next to the current one, at the same location
+	openTab := func() {
+		storeTab()
+		tab := newBrowserTab(tabs[currentTab].state())
+		tabs = append(tabs[:currentTab+1], append([]*browserTab{tab}, tabs[currentTab+1:]...)...)
+		loadTab(currentTab + 1)
+	}
+
+	// closeTab closes the current tab, unless it is the last one
+	closeTab := func() {
+		if len(tabs) == 1 {
+			notify("The last tab cannot be closed")
+			return
+		}
+		for _, pane := range panes {
+			if pane.stop != nil {
+				pane.stop()
+			}
+		}
+		tabs = append(tabs[:currentTab], tabs[currentTab+1:]...)
+		loadTab(min(currentTab, len(tabs)-1))
+	}
+
+	// showTree opens the tree view of bucketName, expanded down to prefix;
+	// back returns to the view it was opened from
+	showTree := func(bucketName, prefix string, back func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var tree *bucketTree
+		tree = newBucketTree(bucketName, prefix, func(prefix string, done func(level treeLevel, err error)) {
+			go func() {
+				var level treeLevel
+				bucketClient, err := clientManager.GetClientForBucket(ctx, bucketName)
+				if err == nil {
+					level, err = listTreeLevel(ctx, bucketClient, bucketName, prefix)
+				}
+				if ctx.Err() != nil {
+					// The tree was closed
+					return
+				}
+				app.QueueUpdateDraw(func() {
+					done(level, err)
+				})
+			}()
+		}, func(key string) {
+			showFileContent(bucketName, key, tree.flex, 0)
+		}, func(key string, isDir bool) {
+			cancel()
+			if isDir {
+				listObjects(bucketName, key, "")
+			} else {
+				listObjects(bucketName, parentPrefix(key), key)
+			}
+		}, func() {
+			cancel()
+			back()
+		})
+		showView(tree.flex)
+	}
+
+	// showUsage opens the usage screen of prefix, breaking down the space
+	// used under it; back returns to the view it was opened from
+	var showUsage func(bucketName, prefix string, back func())
+	showUsage = func(bucketName, prefix string, back func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var view *usageView
+		view = newUsageView(bucketName, prefix, func(child string) {
+			showUsage(bucketName, child, func() {
+				showView(view.flex)
+			})
+		}, func(prefix string) {
+			cancel()
+			listObjects(bucketName, prefix, "")
+		}, func() {
+			cancel()
+			back()
+		})
+		showView(view.flex)
+
+		go func() {
+			var breakdown *usageBreakdown
+			bucketClient, err := clientManager.GetClientForBucket(ctx, bucketName)
+			if err == nil {
+				breakdown, err = computeUsageBreakdown(ctx, bucketClient, bucketName, prefix, func(total dirUsage) {
+					app.QueueUpdateDraw(func() {
+						view.progress(total)
+					})
+				})
+			}
+			if ctx.Err() != nil {
+				// The screen was left
+				return
+			}
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					view.fail(err)
+					return
+				}
+				view.show(breakdown)
+
+				// The totals are the sizes of these folders in the listings
+				for child, usage := range breakdown.children {
+					if child != "" {
+						dirUsages[usageKey(bucketName, child)] = *usage
+					}
+				}
+				if prefix != "" {
+					dirUsages[usageKey(bucketName, prefix)] = breakdown.total
+				}
+			})
+		}()
+	}
+
+	// showBookmarks opens the bookmarks picker; back returns to the view it
+	// was opened from
+	showBookmarks := func(back func()) {
+		picker := showBookmarksPicker(app, bookmarks, func(b Bookmark) {
+			listObjects(b.Bucket, b.Prefix, "")
+		}, func(updated []Bookmark) error {
+			bookmarks = updated
+			return storeBookmarks()
+		}, back)
+		app.SetRoot(picker, true)
+	}
+	showFileContent = func(bucketName, objectKey string, previousFlex *tview.Flex, scrollRow int) {
+		// Update current state
+		currentState.CurrentBucket = bucketName
+		currentState.CurrentPrefix = strings.TrimSuffix(objectKey, filepath.Base(objectKey))
+		currentState.SelectedKey = objectKey
+		currentState.ViewerKey = objectKey
+		currentState.ViewerOffset = scrollRow
+		saveState(currentState)
+
+		// Determine if this might be an image file for better loading message
+		loadingMessage := "Loading file content..."
+		if isImageFile(objectKey) {
+			loadingMessage = "Loading image and converting to ASCII art..."
+		}
+
+		textView := tview.NewTextView().
+			SetText(loadingMessage).
+			SetDynamicColors(true)
+
+		textView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			if event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyLeft {
+				viewerScroll = nil
+				currentState.ViewerKey = ""
+				currentState.ViewerOffset = 0
+				saveState(currentState)
+				showView(previousFlex)
+				return nil
+			}
+			if event.Key() == tcell.KeyRune && event.Rune() == ' ' {
+				// Scroll down a page
+				row, col := textView.GetScrollOffset()
+				_, _, _, height := textView.GetRect()
+				textView.ScrollTo(row+height-1, col)
+				return nil
+			}
+			return event
+		})
+
+		go func() {
+			// Get region-specific client for this bucket
+			bucketClient, err := clientManager.GetClientForBucket(context.TODO(), bucketName)
+			if err != nil {
+				app.QueueUpdateDraw(func() {
+					textView.SetText(fmt.Sprintf("Error getting client: %v", err))
+				})
+				return
+			}
+
+			body, err := getObjectContent(context.TODO(), bucketClient, bucketName, objectKey)
+			if err != nil {
+				app.QueueUpdateDraw(func() {
+					textView.SetText(fmt.Sprintf("Error: %v", err))
+				})
+				return
+			}
+
+			// Decompress if gzipped
+			decompressed, err := decompressIfGzipped(body, objectKey)
+			if err != nil {
+				app.QueueUpdateDraw(func() {
+					textView.SetText(fmt.Sprintf("Error decompressing: %v", err))
+				})
+				return
+			}
+
+			app.QueueUpdateDraw(func() {
+				// Get current terminal dimensions for optimal ASCII art scaling
+				_, _, width, height := textView.GetRect()
+				if width == 0 {
+					width = getTerminalWidth()
+				}
+				if height == 0 {
+					height = getTerminalHeight()
+				}
+
+				// Try to convert to ASCII art if it's an image
+				if ascii, isImage := convertToASCIIArt(decompressed, objectKey, width, height); isImage {
+					textView.SetText("[green]ASCII Art Preview[white]\n\n" + ascii + "\n\n[yellow]Press ESC or Left Arrow to go back[white]")
+				} else {
+					// Display as regular text
+					content := string(decompressed)
+					if len(content) > 0 {
+						textView.SetText(content)
+					} else {
+						textView.SetText("[yellow]File is empty or contains binary data[white]")
+					}
+				}
+				textView.ScrollTo(scrollRow, 0)
+			})
+		}()
+
+		viewerScroll = func() int {
+			row, _ := textView.GetScrollOffset()
+			return row
+		}
+		showView(textView)
+	}
+	listObjects = func(bucketName, prefix, selectKey string) {
+		// The history of the pane the listing is shown in, which stays its
+		// own when another pane or tab becomes active
+		paneHistory := history
+		paneHistory.visit(bucketName, prefix)
+
+		// Update current state
+		currentState.CurrentBucket = bucketName
+		currentState.CurrentPrefix = prefix
+		currentState.SelectedKey = selectKey
+		currentState.ViewerKey = ""
+		currentState.ViewerOffset = 0
+		saveState(currentState)
+
+		// Each listing has its own path header, as two panes may show
+		// listings side by side
+		currentPath := fmt.Sprintf("s3://%s/%s", bucketName, prefix)
+		text := tview.NewTextView().
+			SetTextAlign(tview.AlignCenter).
+			SetText(currentPath)
+		// Create table with proper columns
+		objectTable := tview.NewTable().
+			SetBorders(false).
+			SetSelectable(true, false)
+
+		// Store object entries for proper key handling. loadedEntries holds
+		// every entry loaded so far and objectEntries those shown in the
+		// table, i.e. the ones passing the filter, in row order.
+		var loadedEntries []ObjectEntry
+		var objectEntries []ObjectEntry
+		var filter *entryFilter
+
+		// The table title, declared early as loading pages updates it
+		var defaultTitle func() string
+
+		// The order of the table, shared by all listings and persisted
+		order := entrySort{field: currentState.SortField, descending: currentState.SortDescending}
+		if order.field == "" {
+			order.field = sortByName
+		}
+
+		// Keys of the entries marked for batch operations
+		marked := make(map[string]bool)
+
+		// Directories already listed, so that a folder marker object and the
+		// common prefix it creates are shown only once
+		seenDirectories := make(map[string]bool)
+
+		// Pagination state: the token for the next page of the listing and
+		// whether a page request is currently in flight. listingGeneration is
+		// bumped on every refresh so that pages from a stale listing are dropped.
+		// listingErr is set while the last page request failed; the token is
+		// kept so that the page can be requested again.
+		var continuationToken *string
+		var loadingMore bool
+		var listingGeneration int
+		var listingErr error
+
+		// loadObjectPage fetches the next page of the listing in the
+		// background. Declared early as selecting the status row retries it.
+		var loadObjectPage func()
+
+		// Folder sizes, computed with 'D' or automatically after 'A', are
+		// worked out one folder at a time in the background. sizeQueue holds
+		// the folders waiting, sizing the one being walked with its total so
+		// far in sizingPartial, and stopSizing cancels the walk. Declared
+		// early as rows and loaded pages use them.
+		var sizeQueue []string
+		sizeQueued := make(map[string]bool)
+		var sizing string
+		var sizingPartial dirUsage
+		var stopSizing context.CancelFunc
+		var spinner int
+		var sizeDirectories func(keys []string)
+
+		objectFlex := tview.NewFlex().
+			SetDirection(tview.FlexRow).
+			AddItem(text, 3, 1, false).
+			AddItem(objectTable, 0, 1, true)
+
+		// Store reference for help dialog
+		currentObjectFlex = objectFlex
+
+		objectTable.SetSelectedFunc(func(row, column int) {
+			if row > 0 && row-1 < len(objectEntries) { // Skip header row
+				entry := objectEntries[row-1]
+				if entry.IsDirectory {
+					listObjects(bucketName, entry.Key, "")
+				} else {
+					showFileContent(bucketName, entry.Key, objectFlex, 0)
+				}
+			} else if row == len(objectEntries)+1 && listingErr != nil {
+				// Retry the page that failed to load
+				loadObjectPage()
+			}
+		})
+
+		// setListingStatus renders the pagination indicator in the row after
+		// the last entry, or removes it once the listing is complete
+		setListingStatus := func() {
+			statusRow := len(objectEntries) + 1
+			switch {
+			case loadingMore:
+				objectTable.SetCell(statusRow, 0, tview.NewTableCell(fmt.Sprintf("Loading more… (%d loaded)", len(loadedEntries))).SetTextColor(tcell.ColorGray).SetSelectable(false))
+			case listingErr != nil:
+				// Selectable, so that moving onto it retries
+				objectTable.SetCell(statusRow, 0, tview.NewTableCell(fmt.Sprintf("Failed to load more after %d: %v (select this row or press Ctrl-L to retry)", len(loadedEntries), listingErr)).SetTextColor(tcell.ColorRed))
+			case continuationToken != nil:
+				objectTable.SetCell(statusRow, 0, tview.NewTableCell(fmt.Sprintf("%d loaded, more available, scroll down for more", len(loadedEntries))).SetTextColor(tcell.ColorGray).SetSelectable(false))
+			default:
+				if objectTable.GetRowCount() > statusRow {
+					objectTable.RemoveRow(statusRow)
+				}
+				return
+			}
+			objectTable.SetCell(statusRow, 1, tview.NewTableCell("").SetSelectable(false))
+			objectTable.SetCell(statusRow, 2, tview.NewTableCell("").SetSelectable(false))
+			objectTable.SetCell(statusRow, 3, tview.NewTableCell("").SetSelectable(false))
+		}
+
+		// setObjectRow renders an entry into the given table row. Directories
+		// are shown in blue and marked entries are highlighted in yellow.
+		setObjectRow := func(row int, entry ObjectEntry) {
+			name, size, date, class := entry.Key, "DIR", "", ""
+			color := tview.Styles.PrimaryTextColor
+			if entry.IsDirectory {
+				color = tcell.ColorBlue
+				usage, sized := dirUsages[usageKey(bucketName, entry.Key)]
+				switch {
+				case entry.Key == sizing:
+					size = spinnerFrame(spinner) + " " + sizingPartial.String()
+				case sizeQueued[entry.Key]:
+					size = "DIR (queued)"
+				case sized:
+					size = usage.String()
+					if !usage.newest.IsZero() {
+						date = formatDate(&usage.newest)
+					}
+				}
+			} else {
+				size = formatFileSize(entry.Size)
+				date = formatDate(entry.LastModified)
+				class = entry.StorageClass
+			}
+			if marked[entry.Key] {
+				name = "* " + name
+				color = tcell.ColorYellow
+			}
+
+			objectTable.SetCell(row, 0, tview.NewTableCell(name).SetTextColor(color))
+			objectTable.SetCell(row, 1, tview.NewTableCell(size).SetTextColor(color))
+			objectTable.SetCell(row, 2, tview.NewTableCell(date).SetTextColor(color))
+			objectTable.SetCell(row, 3, tview.NewTableCell(class).SetTextColor(color))
+		}
+
+		// setHeaders renders the header row, marking the sorted column
+		setHeaders := func() {
+			for i, header := range []string{"Name", "Size", "Modified", "Class"} {
+				if i == order.field.column() {
+					header += " " + order.indicator()
+				}
+				objectTable.SetCell(0, i, tview.NewTableCell(header).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+			}
+		}
+
+		// renderRows shows the loaded entries passing the filter in the
+		// current order, keeping the selected entry selected if it is still
+		// shown. It returns the selected row.
+		renderRows := func() int {
+			selected := ""
+			if row, _ := objectTable.GetSelection(); row > 0 && row-1 < len(objectEntries) {
+				selected = objectEntries[row-1].Key
+			}
+
+			objectEntries = filterEntries(loadedEntries, filter)
+			for objectTable.GetRowCount() > 1 {
+				objectTable.RemoveRow(objectTable.GetRowCount() - 1)
+			}
+			selectedRow := 1
+			for i, entry := range objectEntries {
+				setObjectRow(i+1, entry)
+				if entry.Key == selected {
+					selectedRow = i + 1
+				}
+			}
+			setListingStatus()
+			objectTable.Select(selectedRow, 0)
+			return selectedRow
+		}
+
+		// unsizedDirectories returns the keys of the directories among
+		// entries whose size has not been computed
+		unsizedDirectories := func(entries []ObjectEntry) []string {
+			var keys []string
+			for _, entry := range entries {
+				if _, sized := dirUsages[usageKey(bucketName, entry.Key)]; entry.IsDirectory && !sized {
+					keys = append(keys, entry.Key)
+				}
+			}
+			return keys
+		}
+
+		// insertEntry adds an entry to the loaded ones and, if it passes the
+		// filter, inserts its row in place, keeping the selected entry selected
+		insertEntry := func(entry ObjectEntry) {
+			i := entryPosition(loadedEntries, entry, order)
+			loadedEntries = append(loadedEntries, ObjectEntry{})
+			copy(loadedEntries[i+1:], loadedEntries[i:])
+			loadedEntries[i] = entry
+			if !filter.matches(entryBaseName(entry.Key)) {
+				return
+			}
+
+			i = entryPosition(objectEntries, entry, order)
+			objectEntries = append(objectEntries, ObjectEntry{})
+			copy(objectEntries[i+1:], objectEntries[i:])
+			objectEntries[i] = entry
+			objectTable.InsertRow(i + 1)
+			setObjectRow(i+1, entry)
+			if row, _ := objectTable.GetSelection(); row > i && len(objectEntries) > 1 {
+				objectTable.Select(row+1, 0)
+			}
+		}
+
+		// appendObjects adds the directories and files of one listing page to
+		// the table, sorting them in among the entries already loaded. In the
+		// order S3 lists keys in, the page's files go after those loaded
+		// before, so their rows are inserted without redrawing the others;
+		// in any other order the page is merged in and the rows rendered again.
+		appendObjects := func(objects *s3.ListObjectsV2Output) {
+			entries := listingEntries(objects, prefix, seenDirectories)
+			sortEntries(entries, order)
+			if order.isListingOrder() {
+				for _, entry := range entries {
+					insertEntry(entry)
+				}
+				setListingStatus()
+				if row, _ := objectTable.GetSelection(); row == 0 && len(objectEntries) > 0 {
+					objectTable.Select(1, 0)
+				}
+			} else {
+				loadedEntries = mergeEntries(loadedEntries, entries, order)
+				renderRows()
+			}
+			if currentState.AutoDirSizes {
+				sizeDirectories(unsizedDirectories(entries))
+			}
+		}
+
+		// refreshMarks re-renders all rows after marks have changed
+		refreshMarks := func() {
+			for i, entry := range objectEntries {
+				setObjectRow(i+1, entry)
+			}
+		}
+
+		// selectedEntries returns the marked entries, including those hidden
+		// by the filter, or the entry under the cursor if nothing is marked
+		selectedEntries := func() []ObjectEntry {
+			if entries := markedEntries(loadedEntries, marked); len(entries) > 0 {
+				return entries
+			}
+			row, _ := objectTable.GetSelection()
+			if row > 0 && row-1 < len(objectEntries) { // Skip header row
+				return []ObjectEntry{objectEntries[row-1]}
+			}
+			return nil
+		}
+
+		loadObjectPage = func() {
+			if loadingMore {
+				return
+			}
+			loadingMore = true
+			generation := listingGeneration
+			token := continuationToken
+			setListingStatus()
+
+			go func() {
+				// Get region-specific client for this bucket
+				var objects *s3.ListObjectsV2Output
+				bucketClient, err := clientManager.GetClientForBucket(context.TODO(), bucketName)
+				if err == nil {
+					objects, err = listS3ObjectsPage(context.TODO(), bucketClient, bucketName, prefix, token)
+				}
+
+				app.QueueUpdateDraw(func() {
+					if generation != listingGeneration {
+						// The table was refreshed while this page was loading
+						return
+					}
+					loadingMore = false
+					listingErr = err
+					if err != nil {
+						// Keep the token, so the page can be requested again
+						log.Printf("failed to list objects: %v", err)
+						setListingStatus()
+						return
+					}
+
+					// Update the token first, as rendering the page may
+					// move the selection and request the next one
+					continuationToken = nil
+					if objects.IsTruncated != nil && *objects.IsTruncated {
+						continuationToken = objects.NextContinuationToken
+					}
+
+					firstPage := len(loadedEntries) == 0
+					appendObjects(objects)
+					if filter != nil {
+						objectTable.SetTitle(defaultTitle())
+					}
+					setListingStatus()
 
 					// Select first data row if available
-					if row > 1 {
+					if firstPage && len(objectEntries) > 0 {
 						objectTable.Select(1, 0)
 					}
+
+					// Select the entry to restore, loading further pages
+					// until it turns up
+					if selectKey != "" {
+						for i, entry := range objectEntries {
+							if entry.Key == selectKey {
+								objectTable.Select(i+1, 0)
+								selectKey = ""
+								break
+							}
+						}
+						if continuationToken == nil {
+							selectKey = ""
+						}
+					}
+
+					// Keep loading if the page was too small to scroll toward its end
+					if row, _ := objectTable.GetSelection(); continuationToken != nil && (selectKey != "" || row >= len(objectEntries)-paginationLookahead) {
+						loadObjectPage()
+					}
+				})
+			}()
+		}
+
+		// Update path display when selection changes, and fetch the next page
+		// once the selection gets close to the end of what has been loaded
+		objectTable.SetSelectionChangedFunc(func(row, column int) {
+			if row > 0 && row-1 < len(objectEntries) { // Skip header row
+				filename := objectEntries[row-1].Key
+				path := fmt.Sprintf("s3://%s/%s", bucketName, filename)
+				text.SetText(path)
+				paneHistory.setSelected(bucketName, prefix, filename)
+				// A background refresh of an inactive pane or tab must not
+				// change the selection restored on the next start
+				if panes[activePane].objectFlex == objectFlex {
+					currentState.SelectedKey = filename
+				}
+			}
+			switch {
+			case listingErr != nil && row == len(objectEntries)+1:
+				// Scrolling onto the error row retries the failed page
+				loadObjectPage()
+			case listingErr == nil && continuationToken != nil && row >= len(objectEntries)-paginationLookahead:
+				loadObjectPage()
+			}
+		})
+
+		// Function to populate the table with current data
+		populateObjectTable := func() {
+			objectTable.Clear()
+			loadedEntries = nil // Reset entries
+			objectEntries = nil
+			marked = make(map[string]bool)
+			seenDirectories = make(map[string]bool)
+			continuationToken = nil
+			loadingMore = false
+			listingErr = nil
+			listingGeneration++
+
+			// Add table headers
+			setHeaders()
+
+			loadObjectPage()
+		}
+
+		// defaultTitle returns the table title with the filter, the number of
+		// marked entries and help text
+		defaultTitle = func() string {
+			filterStatus := ""
+			if filter != nil {
+				filterStatus = fmt.Sprintf(" [%s filter %q: %d of %d]", filter.mode, filter.pattern, len(objectEntries), len(loadedEntries))
+			}
+			if len(marked) > 0 {
+				return fmt.Sprintf(" Objects in %s/%s%s [%d marked] (Press 'c' to copy, 'd' to download, 'x' to delete, ESC to clear marks) ", bucketName, prefix, filterStatus, len(marked))
+			}
+			if filter != nil {
+				return fmt.Sprintf(" Objects in %s/%s%s (Press '/' to change the filter, ESC to clear it) ", bucketName, prefix, filterStatus)
+			}
+			return fmt.Sprintf(" Objects in %s/%s (Press 'c' to copy, 'C' for presigned URL, 'd' to download, 'u' to upload, 'x' to delete, space to mark, '/' to filter) ", bucketName, prefix)
+		}
+		objectTable.SetBorder(true).SetTitle(defaultTitle())
+
+		// flashTitle shows a status message in the table title and restores
+		// the help text after the given duration
+		flashTitle := func(message string, duration time.Duration) {
+			objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (%s) ", bucketName, prefix, message))
+			go func() {
+				time.Sleep(duration)
+				app.QueueUpdateDraw(func() {
+					objectTable.SetTitle(defaultTitle())
 				})
 			}()
 		}
 
+		// setEntryRow re-renders the row of the entry with key, if it is shown
+		setEntryRow := func(key string) {
+			for i, entry := range objectEntries {
+				if entry.Key == key {
+					setObjectRow(i+1, entry)
+					return
+				}
+			}
+		}
+
+		// sizeDirectories queues directories to have their sizes computed,
+		// and starts working through the queue unless that is under way
+		sizeDirectories = func(keys []string) {
+			for _, key := range keys {
+				if key != sizing && !sizeQueued[key] {
+					sizeQueue = append(sizeQueue, key)
+					sizeQueued[key] = true
+					setEntryRow(key)
+				}
+			}
+			if stopSizing != nil || len(sizeQueue) == 0 {
+				return
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			stopSizing = cancel
+
+			// Animate the spinner until the queue is done or cancelled
+			go func() {
+				ticker := time.NewTicker(spinnerInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						app.QueueUpdateDraw(func() {
+							if ctx.Err() == nil && sizing != "" {
+								spinner++
+								setEntryRow(sizing)
+							}
+						})
+					}
+				}
+			}()
+
+			go func() {
+				defer cancel()
+				for {
+					var key string
+					app.QueueUpdateDraw(func() {
+						if ctx.Err() != nil {
+							return
+						}
+						if len(sizeQueue) == 0 {
+							stopSizing = nil
+							return
+						}
+						key = sizeQueue[0]
+						sizeQueue = sizeQueue[1:]
+						delete(sizeQueued, key)
+						sizing, sizingPartial = key, dirUsage{}
+						setEntryRow(key)
+					})
+					if key == "" {
+						return
+					}
+
+					var usage dirUsage
+					bucketClient, err := clientManager.GetClientForBucket(ctx, bucketName)
+					if err == nil {
+						usage, err = computeDirUsage(ctx, bucketClient, bucketName, key, func(partial dirUsage) {
+							app.QueueUpdateDraw(func() {
+								if ctx.Err() == nil {
+									sizingPartial = partial
+									setEntryRow(key)
+								}
+							})
+						})
+					}
+
+					app.QueueUpdateDraw(func() {
+						if ctx.Err() != nil {
+							return
+						}
+						sizing = ""
+						if err != nil {
+							flashTitle(fmt.Sprintf("Failed to compute the size of %s: %v", entryBaseName(key), err), 3*time.Second)
+						} else {
+							dirUsages[usageKey(bucketName, key)] = usage
+						}
+						setEntryRow(key)
+					})
+				}
+			}()
+		}
+
+		// cancelSizing stops computing folder sizes and empties the queue
+		cancelSizing := func() {
+			if stopSizing == nil {
+				return
+			}
+			stopSizing()
+			stopSizing = nil
+			keys := append(sizeQueue, sizing)
+			sizeQueue, sizing = nil, ""
+			sizeQueued = make(map[string]bool)
+			for _, key := range keys {
+				setEntryRow(key)
+			}
+		}
+
 		// Set this as the current refresh function for resize handling
 		currentRefreshFunc = populateObjectTable
+		setPane(paneState{
+			bucket:     bucketName,
+			prefix:     prefix,
+			objectFlex: objectFlex,
+			refresh:    populateObjectTable,
+			selected:   selectedEntries,
+			stop:       cancelSizing,
+		})
+
+		// applyFilter shows the loaded entries passing the filter, keeping the
+		// selected entry selected if it is still shown
+		applyFilter := func() {
+			selectedRow := renderRows()
+			objectTable.SetTitle(defaultTitle())
+
+			// Matches may be further down the listing
+			if continuationToken != nil && selectedRow >= len(objectEntries)-paginationLookahead {
+				loadObjectPage()
+			}
+		}
 
-		// Set table title with help text
-		objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Press 'c' to copy, 'C' for presigned URL, 'd' to download) ", bucketName, prefix))
+		// editInline shows an input box in place of the path header above the
+		// table, and restores the header once it is submitted or cancelled
+		editInline := func(title, label, initial string, onSubmit func(text string)) {
+			restore := func() {
+				objectFlex.Clear()
+				objectFlex.AddItem(text, 3, 1, false)
+				objectFlex.AddItem(objectTable, 0, 1, true)
+				app.SetFocus(objectTable)
+			}
+			input := newInputBox(title, label, initial, func(value string) {
+				restore()
+				onSubmit(value)
+			}, restore)
+
+			objectFlex.Clear()
+			objectFlex.AddItem(input, 3, 1, true)
+			objectFlex.AddItem(objectTable, 0, 1, false)
+			app.SetFocus(input)
+		}
+
+		// updateMarks re-renders the rows and title after marks have changed
+		updateMarks := func() {
+			refreshMarks()
+			objectTable.SetTitle(defaultTitle())
+		}
+
+		// promptGlob asks for a glob pattern and marks or unmarks the entries
+		// whose names match it
+		promptGlob := func(mark bool) {
+			title := "Mark entries matching"
+			if !mark {
+				title = "Unmark entries matching"
+			}
+			dialog := showInputDialog(title, "Pattern: ", "*", func(pattern string) {
+				showView(objectFlex)
+				matches, err := markByGlob(objectEntries, marked, pattern, mark)
+				updateMarks()
+				if err != nil {
+					flashTitle(fmt.Sprintf("Invalid pattern: %v", err), 3*time.Second)
+				} else {
+					flashTitle(fmt.Sprintf("%d entries matched %s", matches, pattern), 2*time.Second)
+				}
+			}, func() {
+				showView(objectFlex)
+			})
+			app.SetRoot(dialog, true)
+		}
 
 		// Set up input capture for the object table
 		objectTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -754,19 +2066,11 @@ func main() {
 			}
 			// Handle existing navigation logic
 			if event.Key() == tcell.KeyLeft {
+				// Go up one level, selecting the folder we came from
 				if prefix != "" {
-					newPrefix := prefix[:len(prefix)-1]
-					lastSlash := strings.LastIndex(newPrefix, "/")
-					if lastSlash != -1 {
-						listObjects(bucketName, newPrefix[:lastSlash+1])
-					} else {
-						listObjects(bucketName, "")
-					}
+					listObjects(bucketName, parentPrefix(prefix), prefix)
 				} else {
-					// Returning to bucket view, clear current bucket state
-					currentBucketName = ""
-					currentObjectFlex = nil
-					app.SetRoot(flex, true)
+					showBuckets(bucketName)
 				}
 				return nil
 			} else if event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyRight {
@@ -774,113 +2078,567 @@ func main() {
 				if row > 0 && row-1 < len(objectEntries) { // Skip header row
 					entry := objectEntries[row-1]
 					if entry.IsDirectory {
-						listObjects(bucketName, entry.Key)
+						listObjects(bucketName, entry.Key, "")
 					} else {
-						showFileContent(bucketName, entry.Key, objectFlex)
+						showFileContent(bucketName, entry.Key, objectFlex, 0)
 					}
 				}
 				return nil
 			} else if event.Rune() == 'c' {
-				// Copy S3 URL to clipboard
-				row, _ := objectTable.GetSelection()
-				if row > 0 && row-1 < len(objectEntries) { // Skip header row
-					entry := objectEntries[row-1]
-					s3URL := fmt.Sprintf("s3://%s/%s", bucketName, entry.Key)
-					if err := copyToClipboard(s3URL); err != nil {
-						objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Failed to copy: %v) ", bucketName, prefix, err))
+				// Copy S3 URLs of the selected entries to clipboard, one per line
+				entries := selectedEntries()
+				if len(entries) > 0 {
+					var urls []string
+					for _, entry := range entries {
+						urls = append(urls, fmt.Sprintf("s3://%s/%s", bucketName, entry.Key))
+					}
+					if err := copyToClipboard(strings.Join(urls, "\n")); err != nil {
+						flashTitle(fmt.Sprintf("Failed to copy: %v", err), 2*time.Second)
+					} else if len(urls) == 1 {
+						flashTitle(fmt.Sprintf("Copied: %s", urls[0]), 2*time.Second)
 					} else {
-						objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Copied: %s) ", bucketName, prefix, s3URL))
+						flashTitle(fmt.Sprintf("Copied %d URLs", len(urls)), 2*time.Second)
+					}
+				}
+				return nil
+			} else if event.Rune() == 'd' {
+				// Download selected files and directories
+				entries := selectedEntries()
+				if len(entries) > 0 {
+					label := entryBaseName(entries[0].Key)
+					if len(entries) > 1 {
+						label = fmt.Sprintf("%d entries", len(entries))
+					}
+
+					// Default to the directory of a local pane next to this
+					// one, the last used destination, or the current directory
+					defaultDir := currentState.LastDownloadDir
+					if dir, ok := otherLocalDir(); ok {
+						defaultDir = dir
+					} else if defaultDir == "" {
+						defaultDir, _ = os.Getwd()
+					}
+
+					defaultOptions := downloadOptions{
+						policy:      currentState.CollisionPolicy,
+						keepPartial: currentState.KeepPartial,
+					}
+					dialog := showDownloadDialog(label, defaultDir, defaultOptions, func(destDir string, options downloadOptions) {
+						currentState.LastDownloadDir = destDir
+						currentState.CollisionPolicy = options.policy
+						currentState.KeepPartial = options.keepPartial
+						saveState(currentState)
+
+						showView(objectFlex)
+						queueDownloads(bucketName, prefix, entries, destDir, options)
+						flashTitle(fmt.Sprintf("Queued %d download(s), press 't' for transfers", len(entries)), 3*time.Second)
+					}, func() {
+						showView(objectFlex)
+					})
+					app.SetRoot(dialog, true)
+				}
+				return nil
+			} else if event.Rune() == 'C' {
+				// Generate presigned URLs for the selected files and copy them to clipboard (shift-C)
+				var files []ObjectEntry
+				for _, entry := range selectedEntries() {
+					if !entry.IsDirectory {
+						files = append(files, entry)
 					}
-					// Reset title after 2 seconds
+				}
+				if len(files) > 0 {
 					go func() {
-						time.Sleep(2 * time.Second)
+						var urls []string
+						var err error
+						for _, entry := range files {
+							var presignedURL string
+							presignedURL, err = generatePresignedURL(context.TODO(), clientManager, bucketName, entry.Key)
+							if err != nil {
+								break
+							}
+							urls = append(urls, presignedURL)
+						}
 						app.QueueUpdateDraw(func() {
-							objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Press 'c' to copy, 'C' for presigned URL, 'd' to download) ", bucketName, prefix))
+							if err != nil {
+								flashTitle(fmt.Sprintf("Failed to generate presigned URL: %v", err), 2*time.Second)
+							} else if copyErr := copyToClipboard(strings.Join(urls, "\n")); copyErr != nil {
+								flashTitle(fmt.Sprintf("Failed to copy presigned URL: %v", copyErr), 2*time.Second)
+							} else if len(urls) == 1 {
+								flashTitle("Copied presigned URL", 2*time.Second)
+							} else {
+								flashTitle(fmt.Sprintf("Copied %d presigned URLs", len(urls)), 2*time.Second)
+							}
 						})
 					}()
 				}
 				return nil
-			} else if event.Rune() == 'd' {
-				// Download file to current directory
+			} else if event.Rune() == ' ' || event.Rune() == 'v' {
+				// Toggle the mark on the current entry and move to the next one
+				row, _ := objectTable.GetSelection()
+				if row > 0 && row-1 < len(objectEntries) { // Skip header row
+					key := objectEntries[row-1].Key
+					if marked[key] {
+						delete(marked, key)
+					} else {
+						marked[key] = true
+					}
+					updateMarks()
+					if row < len(objectEntries) {
+						objectTable.Select(row+1, 0)
+					}
+				}
+				return nil
+			} else if event.Rune() == '*' {
+				// Invert marks
+				invertMarks(objectEntries, marked)
+				updateMarks()
+				return nil
+			} else if event.Rune() == '+' {
+				promptGlob(true)
+				return nil
+			} else if event.Rune() == '-' {
+				promptGlob(false)
+				return nil
+			} else if event.Key() == tcell.KeyEscape && len(marked) > 0 {
+				// Clear all marks
+				marked = make(map[string]bool)
+				updateMarks()
+				return nil
+			} else if event.Key() == tcell.KeyEscape && filter != nil {
+				// Clear the filter
+				filter = nil
+				applyFilter()
+				return nil
+			} else if event.Rune() == '/' {
+				// Filter the entries as you type
+				mode, pattern := filterSubstring, ""
+				if filter != nil {
+					mode, pattern = filter.mode, filter.pattern
+				}
+				var bar *tview.InputField
+				bar = newFilterBar(mode, pattern, func(f *entryFilter, err error) string {
+					if err != nil {
+						return fmt.Sprintf("Invalid pattern: %v", err)
+					}
+					filter = f
+					applyFilter()
+					return fmt.Sprintf("%d of %d loaded match", len(objectEntries), len(loadedEntries))
+				}, func(key tcell.Key) {
+					if key == tcell.KeyEscape {
+						filter = nil
+						applyFilter()
+					}
+					objectFlex.RemoveItem(bar)
+					app.SetFocus(objectTable)
+				})
+				objectFlex.AddItem(bar, 3, 0, true)
+				app.SetFocus(bar)
+				return nil
+			} else if event.Rune() == 'u' {
+				// Upload a local file or directory into the current prefix,
+				// picking it from the directory of a local pane next to this one
+				cwd, err := os.Getwd()
+				if dir, ok := otherLocalDir(); ok {
+					cwd, err = dir, nil
+				}
+				if err != nil {
+					flashTitle(fmt.Sprintf("Upload failed: %v", err), 3*time.Second)
+					return nil
+				}
+
+				picker := showFilePicker(cwd, func(localPath string) {
+					uploadName := filepath.Base(localPath)
+					showView(objectFlex)
+					queueUpload(localPath, bucketName, prefix)
+					flashTitle(fmt.Sprintf("Queued upload of %s, press 't' for transfers", uploadName), 3*time.Second)
+				}, func() {
+					showView(objectFlex)
+				})
+				app.SetRoot(picker, true)
+				return nil
+			} else if event.Rune() == 'y' || event.Rune() == 'm' {
+				// Yank the selected entries to copy ('y') or move ('m') them
+				// into the location where they are pasted
+				if entries := selectedEntries(); len(entries) > 0 {
+					move := event.Rune() == 'm'
+					yanked = &yankedEntries{
+						bucket:  bucketName,
+						prefix:  prefix,
+						entries: entries,
+						move:    move,
+					}
+					marked = make(map[string]bool)
+					updateMarks()
+					verb := "copy"
+					if move {
+						verb = "move"
+					}
+					flashTitle(fmt.Sprintf("Yanked %d entries to %s, press 'p' to paste", len(entries), verb), 3*time.Second)
+				}
+				return nil
+			} else if event.Rune() == 'p' {
+				// Paste the yanked entries into the current prefix
+				if yanked == nil {
+					flashTitle("Nothing to paste, press 'y' or 'm' to yank entries first", 2*time.Second)
+					return nil
+				}
+				source := *yanked
+				flashTitle("Preparing paste...", 2*time.Second)
+				pasteEntries(source, bucketName, prefix, flashTitle, func() {
+					if source.move {
+						// The sources are gone once moved
+						yanked = nil
+					}
+				})
+				return nil
+			} else if event.Rune() == 'r' {
+				// Rename the entry under the cursor, and everything under it
+				// if it is a directory
 				row, _ := objectTable.GetSelection()
 				if row > 0 && row-1 < len(objectEntries) { // Skip header row
 					entry := objectEntries[row-1]
-					if !entry.IsDirectory {
-						filename := filepath.Base(entry.Key)
-						if filename == "." || filename == "/" {
-							filename = "downloaded_file"
+					oldName := entryBaseName(entry.Key)
+					editInline("Rename "+oldName, "New name: ", oldName, func(newName string) {
+						if newName == oldName {
+							return
 						}
-
-						// We'll restore to objectFlex after download
-						var downloadCancelled bool
-
-						// Show progress window
-						progressModal, updateProgress := showProgressWindow(app, filename, func() {
-							downloadCancelled = true
-						})
-						app.SetRoot(progressModal, true)
+						flashTitle("Preparing rename...", 2*time.Second)
 
 						go func() {
-							err := downloadFile(clientManager, bucketName, entry.Key, updateProgress)
+							var targets []copyTarget
+							bucketClient, err := clientManager.GetClientForBucket(context.TODO(), bucketName)
+							if err == nil {
+								targets, err = planRename(context.TODO(), bucketClient, bucketName, entry, newName)
+							}
 
 							app.QueueUpdateDraw(func() {
-								// Restore original view
-								app.SetRoot(objectFlex, true)
-
-								if downloadCancelled {
-									objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Download cancelled) ", bucketName, prefix))
-								} else if err != nil {
-									objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Download failed: %v) ", bucketName, prefix, err))
-								} else {
-									objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Downloaded: %s) ", bucketName, prefix, filename))
+								if err != nil {
+									flashTitle(fmt.Sprintf("Rename failed: %v", err), 3*time.Second)
+									return
 								}
 
-								// Reset title after 3 seconds
-								go func() {
-									time.Sleep(3 * time.Second)
+								run := func(ctx context.Context, report func(current, total int64, detail string)) error {
+									return renameS3Objects(ctx, bucketClient, bucketName, targets, func(objectsDone, objectsTotal int, current, total int64) {
+										detail := ""
+										if objectsTotal > 1 {
+											detail = fmt.Sprintf("%d of %d objects", objectsDone, objectsTotal)
+										}
+										report(current, total, detail)
+									})
+								}
+								queue.Add("Rename", oldName+" → "+newName, false, run, func(info transferInfo) {
+									switch info.State {
+									case transferDone:
+										notify(fmt.Sprintf("Renamed %s to %s", oldName, newName))
+									case transferFailed:
+										notify(fmt.Sprintf("Rename of %s failed: %v", oldName, info.Err))
+									default:
+										return
+									}
 									app.QueueUpdateDraw(func() {
-										objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Press 'c' to copy, 'C' for presigned URL, 'd' to download) ", bucketName, prefix))
+										refreshListing(bucketName, prefix)
 									})
-								}()
+								})
+								flashTitle(fmt.Sprintf("Renaming %s to %s...", oldName, newName), 2*time.Second)
 							})
 						}()
+					})
+				}
+				return nil
+			} else if event.Rune() == 'g' || event.Rune() == ':' {
+				showGoTo(bucketName, prefix, func() {
+					showView(objectFlex)
+				})
+				return nil
+			} else if event.Rune() == 'b' {
+				showBookmarks(func() {
+					showView(objectFlex)
+				})
+				return nil
+			} else if event.Rune() == 'B' {
+				// Bookmark the current folder
+				editInline(fmt.Sprintf("Bookmark s3://%s/%s", bucketName, prefix), "Name: ", defaultBookmarkName(bucketName, prefix), func(name string) {
+					name = strings.TrimSpace(name)
+					if name == "" {
+						flashTitle("A bookmark needs a name", 3*time.Second)
+						return
+					}
+					var replaced bool
+					bookmarks, replaced = setBookmark(bookmarks, Bookmark{Name: name, Bucket: bucketName, Prefix: prefix})
+					if err := storeBookmarks(); err != nil {
+						flashTitle(fmt.Sprintf("Failed to save bookmarks: %v", err), 3*time.Second)
+					} else if replaced {
+						flashTitle(fmt.Sprintf("Moved bookmark %s here", name), 2*time.Second)
+					} else {
+						flashTitle(fmt.Sprintf("Bookmarked as %s", name), 2*time.Second)
 					}
+				})
+				return nil
+			} else if event.Rune() == 's' || event.Rune() == 'S' {
+				// Cycle the sort column, or reverse the direction
+				if event.Rune() == 's' {
+					order.field = order.field.next()
+				} else {
+					order.descending = !order.descending
 				}
+				currentState.SortField = order.field
+				currentState.SortDescending = order.descending
+				saveState(currentState)
+
+				sortEntries(loadedEntries, order)
+				setHeaders()
+				renderRows()
+				direction := "ascending"
+				if order.descending {
+					direction = "descending"
+				}
+				flashTitle(fmt.Sprintf("Sorted by %s, %s", order.field, direction), 2*time.Second)
 				return nil
-			} else if event.Rune() == 'C' {
-				// Generate presigned URL and copy to clipboard (shift-C)
-				row, _ := objectTable.GetSelection()
-				if row > 0 && row-1 < len(objectEntries) { // Skip header row
-					entry := objectEntries[row-1]
-					if !entry.IsDirectory {
+			} else if event.Rune() == 'D' {
+				// Compute the sizes of the selected folders, or stop
+				if stopSizing != nil {
+					cancelSizing()
+					flashTitle("Stopped computing folder sizes", 2*time.Second)
+					return nil
+				}
+				var keys []string
+				for _, entry := range selectedEntries() {
+					if entry.IsDirectory {
+						delete(dirUsages, usageKey(bucketName, entry.Key))
+						keys = append(keys, entry.Key)
+					}
+				}
+				if len(keys) == 0 {
+					flashTitle("Select a folder to compute its size", 2*time.Second)
+					return nil
+				}
+				sizeDirectories(keys)
+				flashTitle(fmt.Sprintf("Computing the size of %d folder(s), press 'D' again to stop", len(keys)), 3*time.Second)
+				return nil
+			} else if event.Rune() == 'A' {
+				// Toggle computing the sizes of all folders as they are listed
+				currentState.AutoDirSizes = !currentState.AutoDirSizes
+				saveState(currentState)
+				if currentState.AutoDirSizes {
+					sizeDirectories(unsizedDirectories(loadedEntries))
+					flashTitle("Folder sizes are computed automatically, press 'D' to stop", 3*time.Second)
+				} else {
+					cancelSizing()
+					flashTitle("Folder sizes are computed with 'D' only", 2*time.Second)
+				}
+				return nil
+			} else if event.Rune() == 'T' {
+				showTree(bucketName, prefix, func() {
+					showView(objectFlex)
+				})
+				return nil
+			} else if event.Rune() == 'U' {
+				showUsage(bucketName, prefix, func() {
+					// Show the folder sizes found meanwhile
+					refreshMarks()
+					showView(objectFlex)
+				})
+				return nil
+			} else if event.Rune() == 'f' {
+				// Search every key under the current prefix, not just this level
+				location := fmt.Sprintf("s3://%s/%s", bucketName, prefix)
+				dialog := showFindDialog(location, func(criteria findCriteria, summary string) {
+					ctx, cancel := context.WithCancel(context.Background())
+					results := newFindResults(bucketName, prefix, summary)
+
+					resultsFlex := tview.NewFlex().
+						SetDirection(tview.FlexRow).
+						AddItem(text, 3, 1, false).
+						AddItem(results.table, 0, 1, true)
+					text.SetText(location)
+
+					results.table.SetSelectionChangedFunc(func(row, column int) {
+						if key, ok := results.selectedKey(); ok {
+							text.SetText(fmt.Sprintf("s3://%s/%s", bucketName, key))
+						}
+					})
+					results.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+						key, ok := results.selectedKey()
+						switch {
+						case event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyLeft:
+							cancel()
+							text.SetText(location)
+							showView(objectFlex)
+						case event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyRight:
+							if ok && !strings.HasSuffix(key, "/") {
+								showFileContent(bucketName, key, resultsFlex, 0)
+							}
+						case event.Key() == tcell.KeyRune && event.Rune() == 'o':
+							if ok {
+								cancel()
+								listObjects(bucketName, parentPrefix(key), key)
+							}
+						default:
+							return event
+						}
+						return nil
+					})
+					showView(resultsFlex)
+
+					go func() {
+						bucketClient, err := clientManager.GetClientForBucket(ctx, bucketName)
+						if err == nil {
+							err = findS3Objects(ctx, bucketClient, bucketName, prefix, criteria, func(matches []types.Object, scanned int) {
+								app.QueueUpdateDraw(func() {
+									results.add(matches, scanned)
+								})
+							})
+						}
+						if ctx.Err() != nil {
+							// The search was abandoned, nobody is looking at the results
+							return
+						}
+						app.QueueUpdateDraw(func() {
+							results.finish(err)
+						})
+					}()
+				}, func() {
+					showView(objectFlex)
+				})
+				app.SetRoot(dialog, true)
+				return nil
+			} else if event.Rune() == 'n' {
+				// Create a folder in the current prefix
+				editInline("New folder in "+prefix, "Name: ", "", func(name string) {
+					if err := validateEntryName(name); err != nil {
+						flashTitle(fmt.Sprintf("Cannot create folder: %v", err), 3*time.Second)
+						return
+					}
+					key := prefix + name + "/"
+
+					go func() {
+						bucketClient, err := clientManager.GetClientForBucket(context.TODO(), bucketName)
+						if err == nil {
+							err = createS3Folder(context.TODO(), bucketClient, bucketName, key)
+						}
+
+						app.QueueUpdateDraw(func() {
+							if err != nil {
+								flashTitle(fmt.Sprintf("Cannot create folder: %v", err), 3*time.Second)
+								return
+							}
+							populateObjectTable()
+							flashTitle(fmt.Sprintf("Created folder %s", name), 2*time.Second)
+						})
+					}()
+				})
+				return nil
+			} else if event.Key() == tcell.KeyDelete || event.Rune() == 'x' {
+				// Delete the selected objects, including everything under directories
+				if entries := selectedEntries(); len(entries) > 0 {
+					showDeleteDialog(app, clientManager, bucketName, entries, func(message string) {
+						showView(objectFlex)
+						populateObjectTable()
+						flashTitle(message, 3*time.Second)
+					})
+				}
+				return nil
+			}
+			return event
+		})
+
+		showView(objectFlex)
+		populateObjectTable()
+	}
+
+	showGoTo = func(bucketName, prefix string, back func()) {
+		// Folder names under each location, as listed for completion, and
+		// the locations with more folders than were listed. Only touched on
+		// the UI goroutine.
+		folders := make(map[string][]string)
+		truncated := make(map[string]bool)
+		listing := make(map[string]bool)
+
+		var input *tview.InputField
+		var title string
+		showTitle := func(text string) {
+			if input != nil {
+				input.SetTitle(text).SetTitleColor(tview.Styles.TitleColor)
+			}
+		}
+		complete := func(text string) []string {
+			head, partial := splitCompletion(text)
+			b, p, err := resolveGoTo(head, bucketName, prefix)
+			if err != nil {
+				return nil
+			}
+
+			var names []string
+			if b == "" {
+				for _, bucket := range allBuckets {
+					names = append(names, *bucket.Name)
+				}
+				if !strings.HasPrefix(head, "s3://") {
+					head = "s3://"
+				}
+				showTitle(title)
+			} else {
+				location := b + "/" + p
+				var ok bool
+				if names, ok = folders[location]; !ok {
+					if !listing[location] {
+						listing[location] = true
 						go func() {
-							presignedURL, err := generatePresignedURL(context.TODO(), clientManager, bucketName, entry.Key)
+							var found []string
+							var more bool
+							bucketClient, err := clientManager.GetClientForBucket(context.TODO(), b)
+							if err == nil {
+								found, more, err = listGoToFolders(context.TODO(), bucketClient, b, p)
+							}
 							app.QueueUpdateDraw(func() {
 								if err != nil {
-									objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Failed to generate presigned URL: %v) ", bucketName, prefix, err))
-								} else if copyErr := copyToClipboard(presignedURL); copyErr != nil {
-									objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Failed to copy presigned URL: %v) ", bucketName, prefix, copyErr))
-								} else {
-									objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Copied presigned URL) ", bucketName, prefix))
+									found = nil
+								}
+								folders[location] = found
+								truncated[location] = more
+								if input != nil {
+									input.Autocomplete()
 								}
-								// Reset title after 2 seconds
-								go func() {
-									time.Sleep(2 * time.Second)
-									app.QueueUpdateDraw(func() {
-										objectTable.SetTitle(fmt.Sprintf(" Objects in %s/%s (Press 'c' to copy, 'C' for presigned URL, 'd' to download) ", bucketName, prefix))
-									})
-								}()
 							})
 						}()
 					}
+					return nil
+				}
+				if truncated[location] {
+					showTitle(fmt.Sprintf(" Only the first %d folders of s3://%s are completed (Tab: complete, Enter: go, ESC: cancel) ", len(names), location))
+				} else {
+					showTitle(title)
 				}
-				return nil
 			}
-			return event
-		})
 
-		app.SetRoot(objectFlex, true)
-		populateObjectTable()
+			var completions []string
+			for _, name := range fuzzyRank(names, partial) {
+				if len(completions) == maxGoToCompletions {
+					break
+				}
+				completions = append(completions, head+name+"/")
+			}
+			return completions
+		}
+
+		initial := "s3://"
+		if bucketName != "" {
+			initial += bucketName + "/" + prefix
+		}
+		input = newGoToPrompt(initial, complete, func(text string) {
+			b, p, err := resolveGoTo(text, bucketName, prefix)
+			if err != nil {
+				input.SetTitle(fmt.Sprintf(" %v ", err)).SetTitleColor(tcell.ColorRed)
+				return
+			}
+			if b == "" {
+				showBuckets("")
+				return
+			}
+			listObjects(b, p, "")
+		}, back)
+		title = input.GetTitle()
+		app.SetRoot(centered(input, 100, 3), true)
 	}
 
 	bucketTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -888,8 +2646,62 @@ func main() {
 			row, _ := bucketTable.GetSelection()
 			if row > 0 && row-1 < len(bucketEntries) { // Skip header row
 				bucketName := *bucketEntries[row-1].Name
-				listObjects(bucketName, "")
+				listObjects(bucketName, "", "")
+			}
+		} else if event.Rune() == '/' {
+			// Filter the buckets as you type
+			mode, pattern := filterSubstring, ""
+			if bucketFilter != nil {
+				mode, pattern = bucketFilter.mode, bucketFilter.pattern
+			}
+			var bar *tview.InputField
+			bar = newFilterBar(mode, pattern, func(f *entryFilter, err error) string {
+				if err != nil {
+					return fmt.Sprintf("Invalid pattern: %v", err)
+				}
+				bucketFilter = f
+				renderBuckets()
+				return fmt.Sprintf("%d of %d match", len(bucketEntries), len(allBuckets))
+			}, func(key tcell.Key) {
+				if key == tcell.KeyEscape {
+					bucketFilter = nil
+					renderBuckets()
+				}
+				flex.RemoveItem(bar)
+				app.SetFocus(bucketTable)
+			})
+			flex.AddItem(bar, 3, 0, true)
+			app.SetFocus(bar)
+			return nil
+		} else if event.Rune() == 'g' || event.Rune() == ':' {
+			// Jump straight to a bucket or prefix
+			showGoTo("", "", func() {
+				showView(flex)
+			})
+			return nil
+		} else if event.Rune() == 'b' {
+			showBookmarks(func() {
+				showView(flex)
+			})
+			return nil
+		} else if event.Rune() == 'T' {
+			if row, _ := bucketTable.GetSelection(); row > 0 && row-1 < len(bucketEntries) {
+				showTree(*bucketEntries[row-1].Name, "", func() {
+					showView(flex)
+				})
+			}
+			return nil
+		} else if event.Rune() == 'U' {
+			if row, _ := bucketTable.GetSelection(); row > 0 && row-1 < len(bucketEntries) {
+				showUsage(*bucketEntries[row-1].Name, "", func() {
+					showView(flex)
+				})
 			}
+			return nil
+		} else if event.Key() == tcell.KeyEscape && bucketFilter != nil {
+			bucketFilter = nil
+			renderBuckets()
+			return nil
 		}
 		return event
 	})
@@ -912,9 +2724,96 @@ func main() {
 			app.Stop()
 			return nil
 		}
-		if event.Key() == tcell.KeyRune && event.Rune() == 'q' {
-			printCurrentURL()
-			app.Stop()
+		// Let input fields and other form items receive all other keys
+		if _, ok := app.GetFocus().(tview.FormItem); ok {
+			return event
+		}
+		// 'q' quits from the browser only, not while a dialog, picker or
+		// prompt is shown in place of it
+		if event.Key() == tcell.KeyRune && event.Rune() == 'q' && mainLayout.HasFocus() {
+			// Ask before abandoning transfers that have not finished
+			unfinished := 0
+			for _, info := range queue.Jobs() {
+				if !info.State.finished() {
+					unfinished++
+				}
+			}
+			if unfinished == 0 {
+				printCurrentURL()
+				app.Stop()
+				return nil
+			}
+			// The browser is the root, as checked above; return to it and
+			// to what had the focus in it
+			root, focus := mainLayout, app.GetFocus()
+			dialog := showConfirmDialog(fmt.Sprintf("%d transfer(s) have not finished. Quit anyway?", unfinished), "Quit", func() {
+				printCurrentURL()
+				app.Stop()
+			}, func() {
+				app.SetRoot(root, true)
+				app.SetFocus(focus)
+			})
+			app.SetRoot(dialog, true)
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 't' && mainLayout.HasFocus() {
+			toggleTransfersPanel()
+			return nil
+		}
+		// Dual-pane layout: '|' toggles it, Tab switches panes and F5/F6
+		// copy/move from the active pane to the other one. Ctrl-T and
+		// Ctrl-W open and close tabs, '{' and '}' switch between them.
+		if content.HasFocus() {
+			switch {
+			case event.Key() == tcell.KeyRune && event.Rune() == '|':
+				toggleDualPane()
+				return nil
+			case event.Key() == tcell.KeyTab && dualPane:
+				activatePane(1 - activePane)
+				return nil
+			case (event.Key() == tcell.KeyF5 || event.Key() == tcell.KeyF6) && dualPane:
+				transferToOtherPane(event.Key() == tcell.KeyF6)
+				return nil
+			case event.Key() == tcell.KeyCtrlT:
+				openTab()
+				return nil
+			case event.Key() == tcell.KeyCtrlW:
+				closeTab()
+				return nil
+			case event.Key() == tcell.KeyRune && (event.Rune() == '{' || event.Rune() == '}'):
+				if event.Rune() == '{' {
+					switchTab(currentTab - 1)
+				} else {
+					switchTab(currentTab + 1)
+				}
+				return nil
+			case event.Key() == tcell.KeyRune && event.Rune() == 'L' && panes[activePane].local == nil:
+				// Browse the local filesystem in this pane
+				dir := currentState.LastDownloadDir
+				if dir == "" {
+					dir, _ = os.Getwd()
+				}
+				showLocal(dir)
+				return nil
+			}
+		}
+		// Go back and forward through the visited locations
+		alt := event.Modifiers()&tcell.ModAlt != 0
+		back := (event.Key() == tcell.KeyRune && event.Rune() == '[') || (event.Key() == tcell.KeyLeft && alt)
+		forward := (event.Key() == tcell.KeyRune && event.Rune() == ']') || (event.Key() == tcell.KeyRight && alt)
+		browsing := flex.HasFocus() || (currentObjectFlex != nil && currentObjectFlex.HasFocus())
+		if (back || forward) && browsing {
+			move := history.forward
+			if back {
+				move = history.back
+			}
+			if loc, ok := move(); ok {
+				if loc.bucket == "" {
+					showBuckets(loc.selected)
+				} else {
+					listObjects(loc.bucket, loc.prefix, loc.selected)
+				}
+			}
 			return nil
 		}
 		if event.Key() == tcell.KeyRune && event.Rune() == '?' {
@@ -923,13 +2822,8 @@ func main() {
 
 			// Store function to restore previous view
 			restorePreviousView := func() {
-				if currentBucketName != "" && currentObjectFlex != nil {
-					// We're in object view
-					app.SetRoot(currentObjectFlex, true)
-				} else if currentMainFlex != nil {
-					// We're in bucket view
-					app.SetRoot(currentMainFlex, true)
-				}
+				app.SetRoot(mainLayout, true)
+				activatePane(activePane)
 			}
 
 			app.SetRoot(helpModal, true)
@@ -948,22 +2842,41 @@ func main() {
 		return event
 	})
 
+	// Reopen the tabs of the last session. The active tab opens at the
+	// location chosen below, the others when they are first shown.
+	if n := len(savedState.Tabs); n > 1 && savedState.ActiveTab >= 0 && savedState.ActiveTab < n {
+		tabs = make([]*browserTab, n)
+		for i, state := range savedState.Tabs {
+			tabs[i] = newBrowserTab(state)
+		}
+		currentTab = savedState.ActiveTab
+		renderTabs()
+	}
+
 	// Handle navigation: URL argument takes precedence over saved state
-	var targetBucket, targetPrefix string
+	var targetBucket, targetPrefix, selectKey, viewerKey string
+	var viewerOffset int
 	var shouldNavigate bool
 
-	if s3URL != "" {
-		// URL argument provided - use it
+	if s3URL != "" || *bookmarkName != "" {
+		// URL or bookmark argument provided - use it
 		targetBucket = currentState.CurrentBucket
 		targetPrefix = currentState.CurrentPrefix
 		shouldNavigate = true
 	} else if savedState.CurrentBucket != "" {
-		// No URL argument but saved state exists - use saved state
+		// No URL argument but saved state exists - use saved state,
+		// including the selected entry and the object being viewed
 		targetBucket = savedState.CurrentBucket
 		targetPrefix = savedState.CurrentPrefix
+		selectKey = savedState.SelectedKey
+		viewerKey = savedState.ViewerKey
+		viewerOffset = savedState.ViewerOffset
 		shouldNavigate = true
 	}
 
+	// A URL not ending in "/" may point to an object rather than a prefix
+	objectURL := s3URL != "" && !strings.HasSuffix(s3URL, "/") && targetPrefix != ""
+
 	if shouldNavigate {
 		go func() {
 			// Wait for buckets to be loaded first
@@ -981,20 +2894,50 @@ func main() {
 				}
 			}
 
-			if bucketExists {
-				app.QueueUpdateDraw(func() {
-					listObjects(targetBucket, targetPrefix)
-				})
+			if !bucketExists {
+				return
+			}
+
+			// Open an object URL in the viewer, with its folder behind it
+			if objectURL {
+				key := strings.TrimSuffix(targetPrefix, "/")
+				bucketClient, err := clientManager.GetClientForBucket(context.TODO(), targetBucket)
+				if err == nil {
+					var isObject bool
+					isObject, err = isS3Object(context.TODO(), bucketClient, targetBucket, key)
+					if isObject {
+						targetPrefix = parentPrefix(key)
+						selectKey = key
+						viewerKey = key
+					}
+				}
+				if err != nil {
+					log.Printf("failed to check whether %s is an object: %v", key, err)
+				}
 			}
+
+			app.QueueUpdateDraw(func() {
+				listObjects(targetBucket, targetPrefix, selectKey)
+				if viewerKey != "" {
+					showFileContent(targetBucket, viewerKey, currentObjectFlex, viewerOffset)
+				}
+			})
 		}()
 	}
 
 	// Run the application
-	if err := app.SetRoot(flex, true).Run(); err != nil {
+	if err := app.SetRoot(mainLayout, true).Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Save the selection and viewer position, which are not saved as they
+	// change
+	if viewerScroll != nil {
+		currentState.ViewerOffset = viewerScroll()
+	}
+	saveState(currentState)
+
 	// Print URL on normal exit
 	printCurrentURL()
 }
@@ -2,63 +2,181 @@ package main
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/rivo/tview"
 )
 
-// showProgressWindow displays a progress window for file download
-func showProgressWindow(app *tview.Application, filename string, onCancel func()) (*tview.Modal, func(current, total int64)) {
+// progressRefreshInterval is the minimum time between redraws of a progress
+// window, however often progress is reported
+const progressRefreshInterval = 100 * time.Millisecond
+
+// formatTransferStats renders the elapsed time, rate and time remaining of a
+// transfer on one line. The rate and remaining time are left out while they
+// are unknown.
+func formatTransferStats(elapsed time.Duration, rate float64, eta time.Duration) string {
+	stats := "Elapsed " + formatDuration(elapsed)
+	if rate > 0 {
+		stats += ", " + formatRate(rate)
+	}
+	if eta > 0 {
+		stats += ", " + formatDuration(eta) + " left"
+	}
+	return stats
+}
+
+// formatProgressText renders the progress window text for a transfer.
+// detail is an optional extra line, e.g. the number of files done.
+func formatProgressText(action, filename, detail string, current, total int64) string {
+	if detail != "" {
+		detail = "\n" + detail
+	}
+
+	if total > 0 {
+		percentage := float64(current) * 100.0 / float64(total)
+		barWidth := 40
+		filled := int(percentage * float64(barWidth) / 100.0)
+
+		bar := "["
+		for i := 0; i < barWidth; i++ {
+			if i < filled {
+				bar += "█"
+			} else {
+				bar += "░"
+			}
+		}
+		bar += "]"
+
+		return fmt.Sprintf("%s: %s\n\n%s\n%.1f%% (%s / %s)%s",
+			action,
+			filename,
+			bar,
+			percentage,
+			formatBytes(current),
+			formatBytes(total),
+			detail)
+	}
+
+	bar := "[░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░]"
+	return fmt.Sprintf("%s: %s\n\n%s\n%s... (%s)%s",
+		action,
+		filename,
+		bar,
+		action,
+		formatBytes(current),
+		detail)
+}
+
+// redrawThrottle coalesces redraw requests so that a window is redrawn at
+// most every interval, always once more after the last request
+type redrawThrottle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	now      func() time.Time
+	// schedule runs draw once wait has passed, or right away if it is not
+	// positive, without blocking the caller
+	schedule func(wait time.Duration, draw func())
+	lastDraw time.Time
+	pending  bool
+}
+
+// newRedrawThrottle creates a throttle queuing draws on app's event loop
+func newRedrawThrottle(app *tview.Application, interval time.Duration) *redrawThrottle {
+	return &redrawThrottle{
+		interval: interval,
+		now:      time.Now,
+		schedule: func(wait time.Duration, draw func()) {
+			if wait <= 0 {
+				go app.QueueUpdateDraw(draw)
+				return
+			}
+			time.AfterFunc(wait, func() {
+				app.QueueUpdateDraw(draw)
+			})
+		},
+	}
+}
+
+// request schedules draw, unless a redraw is already pending. Callers update
+// the values drawn before requesting, so the pending redraw shows them.
+func (t *redrawThrottle) request(draw func()) {
+	t.mu.Lock()
+	if t.pending {
+		t.mu.Unlock()
+		return
+	}
+	t.pending = true
+	wait := t.interval - t.now().Sub(t.lastDraw)
+	t.mu.Unlock()
+
+	t.schedule(wait, draw)
+}
+
+// drawn records that the pending redraw is running. It is called before the
+// values are read, so that a request made meanwhile schedules another.
+func (t *redrawThrottle) drawn() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = false
+	t.lastDraw = t.now()
+}
+
+// showProgressWindow creates a progress window for a file transfer and a
+// function that updates it from any goroutine. action describes the
+// transfer, e.g. "Downloading" or "Deleting". Updates are coalesced so the
+// window is redrawn at most every progressRefreshInterval, always ending
+// with the latest values.
+func showProgressWindow(app *tview.Application, action, filename string, onCancel func()) (*tview.Modal, func(current, total int64)) {
+	var mu sync.Mutex
 	cancelled := false
+	started := time.Now()
+	var meter rateMeter
+	throttle := newRedrawThrottle(app, progressRefreshInterval)
+	var latestCurrent, latestTotal int64
 
 	modal := tview.NewModal().
-		SetText(fmt.Sprintf("Downloading: %s\n\nPreparing download...", filename)).
+		SetText(fmt.Sprintf("%s: %s\n\nPreparing...", action, filename)).
 		AddButtons([]string{"Cancel"}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
 			if buttonLabel == "Cancel" && onCancel != nil {
+				mu.Lock()
 				cancelled = true
+				mu.Unlock()
 				onCancel()
 			}
 		})
 
-	updateProgress := func(current, total int64) {
+	// draw renders the latest values. It runs in the application's event loop.
+	draw := func() {
+		throttle.drawn()
+		mu.Lock()
 		if cancelled {
+			mu.Unlock()
 			return
 		}
+		now := time.Now()
+		meter.add(now, latestCurrent)
+		rate := meter.rate()
+		text := formatProgressText(action, filename, "", latestCurrent, latestTotal)
+		text += "\n" + formatTransferStats(now.Sub(started), rate, estimateRemaining(latestCurrent, latestTotal, rate))
+		mu.Unlock()
 
-		app.QueueUpdateDraw(func() {
-			var progressText string
-
-			if total > 0 {
-				percentage := float64(current) * 100.0 / float64(total)
-				barWidth := 40
-				filled := int(percentage * float64(barWidth) / 100.0)
-
-				bar := "["
-				for i := 0; i < barWidth; i++ {
-					if i < filled {
-						bar += "█"
-					} else {
-						bar += "░"
-					}
-				}
-				bar += "]"
-
-				progressText = fmt.Sprintf("Downloading: %s\n\n%s\n%.1f%% (%s / %s)",
-					filename,
-					bar,
-					percentage,
-					formatBytes(current),
-					formatBytes(total))
-			} else {
-				bar := "[░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░]"
-				progressText = fmt.Sprintf("Downloading: %s\n\n%s\nDownloading... (%s)",
-					filename,
-					bar,
-					formatBytes(current))
-			}
+		modal.SetText(text)
+	}
 
-			modal.SetText(progressText)
-		})
+	updateProgress := func(current, total int64) {
+		mu.Lock()
+		if cancelled {
+			mu.Unlock()
+			return
+		}
+		latestCurrent, latestTotal = current, total
+		mu.Unlock()
+
+		// Draw now if the last redraw was long enough ago, otherwise once the
+		// interval has passed. Either way the transfer does not wait for it.
+		throttle.request(draw)
 	}
 
 	return modal, updateProgress
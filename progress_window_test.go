@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatTransferStats(t *testing.T) {
+	tests := []struct {
+		elapsed  time.Duration
+		rate     float64
+		eta      time.Duration
+		expected string
+	}{
+		{3 * time.Second, 0, 0, "Elapsed 0:03"},
+		{75 * time.Second, 2048, 0, "Elapsed 1:15, 2.0 KB/s"},
+		{time.Hour + 2*time.Second, 1024 * 1024, 90 * time.Second, "Elapsed 1:00:02, 1.0 MB/s, 1:30 left"},
+	}
+
+	for _, test := range tests {
+		if actual := formatTransferStats(test.elapsed, test.rate, test.eta); actual != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, actual)
+		}
+	}
+}
+
+func TestRedrawThrottle(t *testing.T) {
+	now := time.Now()
+	var waits []time.Duration
+	throttle := &redrawThrottle{
+		interval: 100 * time.Millisecond,
+		now:      func() time.Time { return now },
+		schedule: func(wait time.Duration, draw func()) {
+			waits = append(waits, wait)
+		},
+	}
+	draw := func() {}
+
+	// The first request draws right away, and the others wait for it
+	for i := 0; i < 10000; i++ {
+		throttle.request(draw)
+	}
+	if len(waits) != 1 || waits[0] > 0 {
+		t.Fatalf("expected one immediate redraw, got %v", waits)
+	}
+
+	// Once drawn, the next request waits for the rest of the interval
+	throttle.drawn()
+	now = now.Add(30 * time.Millisecond)
+	throttle.request(draw)
+	throttle.request(draw)
+	if len(waits) != 2 || waits[1] != 70*time.Millisecond {
+		t.Fatalf("expected a second redraw in 70ms, got %v", waits)
+	}
+
+	throttle.drawn()
+	now = now.Add(time.Second)
+	throttle.request(draw)
+	if len(waits) != 3 || waits[2] > 0 {
+		t.Errorf("expected an immediate redraw after a quiet second, got %v", waits)
+	}
+}
+
+func TestRateMeterMergesFrequentSamples(t *testing.T) {
+	var meter rateMeter
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		meter.add(start.Add(time.Duration(i)*time.Millisecond), int64(i)*10)
+	}
+
+	if len(meter.samples) > 12 {
+		t.Errorf("expected samples within %v to be merged, got %d samples", rateSampleInterval, len(meter.samples))
+	}
+	if rate := meter.rate(); rate < 9000 || rate > 11000 {
+		t.Errorf("expected about 10000 B/s, got %f", rate)
+	}
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -34,9 +38,23 @@ type S3Client interface {
 	ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
 	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
 	GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
 }
 
+// deleteBatchSize is the maximum number of keys accepted by a single
+// DeleteObjects request
+const deleteBatchSize = 1000
+
 func getBuckets(ctx context.Context, client S3Client) ([]types.Bucket, error) {
 	result, err := client.ListBuckets(ctx, &s3.ListBucketsInput{})
 	if err != nil {
@@ -46,10 +64,18 @@ func getBuckets(ctx context.Context, client S3Client) ([]types.Bucket, error) {
 }
 
 func listS3Objects(ctx context.Context, client S3Client, bucketName, prefix string) (*s3.ListObjectsV2Output, error) {
+	return listS3ObjectsPage(ctx, client, bucketName, prefix, nil)
+}
+
+// listS3ObjectsPage lists a single page of objects under prefix, continuing
+// from continuationToken when it is non-nil. Callers should keep requesting
+// pages with the returned NextContinuationToken while IsTruncated is set.
+func listS3ObjectsPage(ctx context.Context, client S3Client, bucketName, prefix string, continuationToken *string) (*s3.ListObjectsV2Output, error) {
 	delimiter := "/"
 	input := &s3.ListObjectsV2Input{
-		Bucket:    &bucketName,
-		Delimiter: &delimiter,
+		Bucket:            &bucketName,
+		Delimiter:         &delimiter,
+		ContinuationToken: continuationToken,
 	}
 	if prefix != "" {
 		input.Prefix = &prefix
@@ -57,6 +83,193 @@ func listS3Objects(ctx context.Context, client S3Client, bucketName, prefix stri
 	return client.ListObjectsV2(ctx, input)
 }
 
+// listingEntries converts one page of a delimited listing of prefix into
+// entries, directories first. Folders come as common prefixes, including
+// those that only exist as a zero-byte marker object; the marker of prefix
+// itself is listed as an object and skipped. seen records the directories
+// returned so far, so a directory is only returned once across pages.
+func listingEntries(page *s3.ListObjectsV2Output, prefix string, seen map[string]bool) []ObjectEntry {
+	var entries []ObjectEntry
+	addDirectory := func(key string) {
+		if key == prefix || seen[key] {
+			return
+		}
+		seen[key] = true
+		entries = append(entries, ObjectEntry{Key: key, IsDirectory: true})
+	}
+
+	for _, p := range page.CommonPrefixes {
+		addDirectory(*p.Prefix)
+	}
+
+	for _, o := range page.Contents {
+		if *o.Key == prefix {
+			continue
+		}
+		entry := ObjectEntry{
+			Key:          *o.Key,
+			LastModified: o.LastModified,
+		}
+		if o.Size != nil {
+			entry.Size = *o.Size
+		}
+		if o.ETag != nil {
+			entry.ETag = *o.ETag
+		}
+		entry.StorageClass = string(o.StorageClass)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// createS3Folder creates the zero-byte marker object for the folder key,
+// which must end in "/". It fails if anything exists under key already.
+func createS3Folder(ctx context.Context, client S3Client, bucketName, key string) error {
+	existing, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  &bucketName,
+		Prefix:  &key,
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return err
+	}
+	if len(existing.Contents) > 0 {
+		return fmt.Errorf("%s already exists", key)
+	}
+
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &bucketName,
+		Key:           &key,
+		Body:          strings.NewReader(""),
+		ContentLength: aws.Int64(0),
+	})
+	return err
+}
+
+// walkS3Objects calls fn for every object under prefix, following all pages
+// of an undelimited listing. Returning an error from fn stops the walk.
+func walkS3Objects(ctx context.Context, client S3Client, bucketName, prefix string, fn func(object types.Object) error) error {
+	input := &s3.ListObjectsV2Input{
+		Bucket: &bucketName,
+	}
+	if prefix != "" {
+		input.Prefix = &prefix
+	}
+
+	for {
+		result, err := client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return err
+		}
+		for _, object := range result.Contents {
+			if err := fn(object); err != nil {
+				return err
+			}
+		}
+		if result.IsTruncated == nil || !*result.IsTruncated {
+			return nil
+		}
+		input.ContinuationToken = result.NextContinuationToken
+	}
+}
+
+// collectEntryObjects expands the given table entries into the objects they
+// cover: files map to themselves, directories to every object under their
+// prefix (including a zero-byte marker for the directory itself, if any).
+func collectEntryObjects(ctx context.Context, client S3Client, bucketName string, entries []ObjectEntry) ([]types.Object, error) {
+	var objects []types.Object
+	for _, entry := range entries {
+		if !entry.IsDirectory {
+			objects = append(objects, types.Object{
+				Key:          aws.String(entry.Key),
+				Size:         aws.Int64(entry.Size),
+				LastModified: entry.LastModified,
+				ETag:         aws.String(entry.ETag),
+			})
+			continue
+		}
+
+		err := walkS3Objects(ctx, client, bucketName, entry.Key, func(object types.Object) error {
+			objects = append(objects, object)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// deleteS3Objects deletes objects in batches of up to deleteBatchSize keys.
+// onProgress receives the running count and size of deleted objects after
+// each batch. It returns the number of objects deleted; per-key failures
+// reported by S3 are returned as an error after all batches have been sent.
+func deleteS3Objects(ctx context.Context, client S3Client, bucketName string, objects []types.Object, onProgress func(deleted int, deletedBytes int64)) (int, error) {
+	deleted := 0
+	var deletedBytes int64
+	var failures []string
+
+	for start := 0; start < len(objects); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(objects) {
+			end = len(objects)
+		}
+		batch := objects[start:end]
+
+		identifiers := make([]types.ObjectIdentifier, len(batch))
+		for i, object := range batch {
+			identifiers[i] = types.ObjectIdentifier{Key: object.Key}
+		}
+
+		quiet := true
+		result, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: &bucketName,
+			Delete: &types.Delete{
+				Objects: identifiers,
+				Quiet:   &quiet,
+			},
+		})
+		if err != nil {
+			return deleted, err
+		}
+
+		// In quiet mode S3 only reports the keys that could not be deleted
+		failed := make(map[string]bool, len(result.Errors))
+		for _, e := range result.Errors {
+			if e.Key != nil {
+				failed[*e.Key] = true
+				message := "unknown error"
+				if e.Message != nil {
+					message = *e.Message
+				}
+				failures = append(failures, fmt.Sprintf("%s: %s", *e.Key, message))
+			}
+		}
+		for _, object := range batch {
+			if failed[*object.Key] {
+				continue
+			}
+			deleted++
+			if object.Size != nil {
+				deletedBytes += *object.Size
+			}
+		}
+
+		if onProgress != nil {
+			onProgress(deleted, deletedBytes)
+		}
+	}
+
+	if len(failures) > 0 {
+		shown := failures
+		if len(shown) > 3 {
+			shown = append(shown[:3:3], "...")
+		}
+		return deleted, fmt.Errorf("failed to delete %d object(s): %s", len(failures), strings.Join(shown, "; "))
+	}
+	return deleted, nil
+}
+
 func getObjectContent(ctx context.Context, client S3Client, bucketName, objectKey string) ([]byte, error) {
 	input := &s3.GetObjectInput{
 		Bucket: &bucketName,
@@ -71,6 +284,23 @@ func getObjectContent(ctx context.Context, client S3Client, bucketName, objectKe
 	return io.ReadAll(result.Body)
 }
 
+// isS3Object reports whether an object with exactly key exists, telling an
+// object apart from a prefix
+func isS3Object(ctx context.Context, client S3Client, bucketName, key string) (bool, error) {
+	_, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &bucketName,
+		Key:    &key,
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 func getBucketRegion(ctx context.Context, client S3Client, bucketName string) (string, error) {
 	// Check cache first
 	cacheMutex.RLock()
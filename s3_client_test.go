@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 	"testing"
@@ -17,6 +19,17 @@ type mockS3Client struct {
 	ListObjectsV2Func     func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 	GetObjectFunc         func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 	GetBucketLocationFunc func(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
+	HeadObjectFunc        func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+
+	PutObjectFunc               func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUploadFunc   func(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPartFunc              func(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUploadFunc func(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUploadFunc    func(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	DeleteObjectsFunc           func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	CopyObjectFunc              func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	UploadPartCopyFunc          func(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+	GetObjectTaggingFunc        func(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
 }
 
 func (m *mockS3Client) ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
@@ -35,6 +48,46 @@ func (m *mockS3Client) GetBucketLocation(ctx context.Context, params *s3.GetBuck
 	return m.GetBucketLocationFunc(ctx, params, optFns...)
 }
 
+func (m *mockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return m.HeadObjectFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return m.PutObjectFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return m.CreateMultipartUploadFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return m.UploadPartFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return m.CompleteMultipartUploadFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return m.AbortMultipartUploadFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return m.DeleteObjectsFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return m.CopyObjectFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return m.UploadPartCopyFunc(ctx, params, optFns...)
+}
+
+func (m *mockS3Client) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return m.GetObjectTaggingFunc(ctx, params, optFns...)
+}
+
 func TestGetBuckets(t *testing.T) {
 	mockClient := &mockS3Client{
 		ListBucketsFunc: func(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
@@ -97,6 +150,154 @@ func TestListS3Objects(t *testing.T) {
 	}
 }
 
+func TestListS3ObjectsPage(t *testing.T) {
+	var gotToken *string
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			gotToken = params.ContinuationToken
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("logs/2.txt")},
+				},
+				IsTruncated:           aws.Bool(true),
+				NextContinuationToken: aws.String("page-3"),
+			}, nil
+		},
+	}
+
+	objects, err := listS3ObjectsPage(context.TODO(), mockClient, "test-bucket", "logs/", aws.String("page-2"))
+	if err != nil {
+		t.Fatalf("listS3ObjectsPage returned an error: %v", err)
+	}
+
+	if gotToken == nil || *gotToken != "page-2" {
+		t.Fatalf("expected continuation token 'page-2' to be passed through, got %v", gotToken)
+	}
+
+	if objects.NextContinuationToken == nil || *objects.NextContinuationToken != "page-3" {
+		t.Errorf("expected next continuation token 'page-3', got %v", objects.NextContinuationToken)
+	}
+}
+
+func TestWalkS3Objects(t *testing.T) {
+	calls := 0
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			calls++
+			if params.Delimiter != nil {
+				t.Errorf("expected an undelimited listing, got delimiter '%s'", *params.Delimiter)
+			}
+			if params.ContinuationToken == nil {
+				return &s3.ListObjectsV2Output{
+					Contents:              []types.Object{{Key: aws.String("dir/a")}, {Key: aws.String("dir/sub/b")}},
+					IsTruncated:           aws.Bool(true),
+					NextContinuationToken: aws.String("next"),
+				}, nil
+			}
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{{Key: aws.String("dir/sub/c")}},
+			}, nil
+		},
+	}
+
+	var keys []string
+	err := walkS3Objects(context.TODO(), mockClient, "test-bucket", "dir/", func(object types.Object) error {
+		keys = append(keys, *object.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkS3Objects returned an error: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 list calls, got %d", calls)
+	}
+	if strings.Join(keys, ",") != "dir/a,dir/sub/b,dir/sub/c" {
+		t.Errorf("unexpected keys walked: %v", keys)
+	}
+}
+
+func TestDeleteS3ObjectsBatches(t *testing.T) {
+	var batchSizes []int
+	mockClient := &mockS3Client{
+		DeleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			batchSizes = append(batchSizes, len(params.Delete.Objects))
+			return &s3.DeleteObjectsOutput{}, nil
+		},
+	}
+
+	objects := make([]types.Object, deleteBatchSize+5)
+	for i := range objects {
+		objects[i] = types.Object{Key: aws.String(fmt.Sprintf("key-%d", i)), Size: aws.Int64(2)}
+	}
+
+	var lastDeletedBytes int64
+	deleted, err := deleteS3Objects(context.TODO(), mockClient, "test-bucket", objects, func(deleted int, deletedBytes int64) {
+		lastDeletedBytes = deletedBytes
+	})
+	if err != nil {
+		t.Fatalf("deleteS3Objects returned an error: %v", err)
+	}
+
+	if deleted != len(objects) {
+		t.Errorf("expected %d objects deleted, got %d", len(objects), deleted)
+	}
+	if len(batchSizes) != 2 || batchSizes[0] != deleteBatchSize || batchSizes[1] != 5 {
+		t.Errorf("expected batches of %d and 5, got %v", deleteBatchSize, batchSizes)
+	}
+	if lastDeletedBytes != int64(2*len(objects)) {
+		t.Errorf("expected %d deleted bytes, got %d", 2*len(objects), lastDeletedBytes)
+	}
+}
+
+func TestDeleteS3ObjectsReportsFailures(t *testing.T) {
+	mockClient := &mockS3Client{
+		DeleteObjectsFunc: func(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			return &s3.DeleteObjectsOutput{
+				Errors: []types.Error{{Key: aws.String("b"), Message: aws.String("Access Denied")}},
+			}, nil
+		},
+	}
+
+	objects := []types.Object{{Key: aws.String("a")}, {Key: aws.String("b")}}
+	deleted, err := deleteS3Objects(context.TODO(), mockClient, "test-bucket", objects, nil)
+	if err == nil {
+		t.Fatal("expected deleteS3Objects to report the failed key")
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 object deleted, got %d", deleted)
+	}
+	if !strings.Contains(err.Error(), "Access Denied") {
+		t.Errorf("expected error to contain the S3 message, got '%v'", err)
+	}
+}
+
+func TestCollectEntryObjects(t *testing.T) {
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{{Key: aws.String("dir/"), Size: aws.Int64(0)}, {Key: aws.String("dir/x"), Size: aws.Int64(7)}},
+			}, nil
+		},
+	}
+
+	entries := []ObjectEntry{
+		{Key: "dir/", IsDirectory: true},
+		{Key: "file.txt", Size: 3},
+	}
+	objects, err := collectEntryObjects(context.TODO(), mockClient, "test-bucket", entries)
+	if err != nil {
+		t.Fatalf("collectEntryObjects returned an error: %v", err)
+	}
+
+	if len(objects) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(objects))
+	}
+	if *objects[2].Key != "file.txt" || *objects[2].Size != 3 {
+		t.Errorf("expected file entry to map to itself, got %s (%d)", *objects[2].Key, *objects[2].Size)
+	}
+}
+
 func TestGetObjectContent(t *testing.T) {
 	content := "hello world"
 	mockClient := &mockS3Client{
@@ -156,3 +357,98 @@ func TestGetBucketRegionUsEast1(t *testing.T) {
 		t.Errorf("expected region 'us-east-1', got '%s'", region)
 	}
 }
+
+func TestListingEntries(t *testing.T) {
+	seen := make(map[string]bool)
+	// As S3 returns it with the "/" delimiter: the marker of dir/ itself
+	// among the objects, and the folders below it, whether they hold objects
+	// or only a marker, as common prefixes
+	page := &s3.ListObjectsV2Output{
+		CommonPrefixes: []types.CommonPrefix{{Prefix: aws.String("dir/a/")}, {Prefix: aws.String("dir/b/")}},
+		Contents: []types.Object{
+			{Key: aws.String("dir/"), Size: aws.Int64(0)},
+			{Key: aws.String("dir/file.txt"), Size: aws.Int64(4), ETag: aws.String(`"x"`), StorageClass: types.ObjectStorageClassGlacier},
+		},
+	}
+
+	entries := listingEntries(page, "dir/", seen)
+	expected := []ObjectEntry{
+		{Key: "dir/a/", IsDirectory: true},
+		{Key: "dir/b/", IsDirectory: true},
+		{Key: "dir/file.txt", Size: 4, ETag: `"x"`, StorageClass: "GLACIER"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), entries)
+	}
+	for i := range expected {
+		if entries[i].Key != expected[i].Key || entries[i].IsDirectory != expected[i].IsDirectory || entries[i].Size != expected[i].Size || entries[i].ETag != expected[i].ETag || entries[i].StorageClass != expected[i].StorageClass {
+			t.Errorf("entry %d: expected %+v, got %+v", i, expected[i], entries[i])
+		}
+	}
+
+	// Directories already shown on an earlier page are not repeated
+	next := &s3.ListObjectsV2Output{
+		CommonPrefixes: []types.CommonPrefix{{Prefix: aws.String("dir/b/")}, {Prefix: aws.String("dir/c/")}},
+	}
+	entries = listingEntries(next, "dir/", seen)
+	if len(entries) != 1 || entries[0].Key != "dir/c/" {
+		t.Errorf("expected only dir/c/ on the next page, got %+v", entries)
+	}
+}
+
+func TestCreateS3Folder(t *testing.T) {
+	var existing []types.Object
+	var put *s3.PutObjectInput
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if *params.Prefix != "dir/new/" {
+				t.Errorf("expected to check for existing keys under dir/new/, got %s", *params.Prefix)
+			}
+			return &s3.ListObjectsV2Output{Contents: existing}, nil
+		},
+		PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			put = params
+			return &s3.PutObjectOutput{}, nil
+		},
+	}
+
+	if err := createS3Folder(context.TODO(), mockClient, "test-bucket", "dir/new/"); err != nil {
+		t.Fatalf("createS3Folder returned an error: %v", err)
+	}
+	if put == nil || *put.Key != "dir/new/" || *put.ContentLength != 0 {
+		t.Errorf("expected a zero-byte marker at dir/new/, got %+v", put)
+	}
+
+	put = nil
+	existing = []types.Object{{Key: aws.String("dir/new/file.txt")}}
+	if err := createS3Folder(context.TODO(), mockClient, "test-bucket", "dir/new/"); err == nil {
+		t.Error("expected creating an existing folder to fail")
+	}
+	if put != nil {
+		t.Error("expected no marker to be written for an existing folder")
+	}
+}
+
+func TestIsS3Object(t *testing.T) {
+	mockClient := &mockS3Client{
+		HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			switch *params.Key {
+			case "logs/app.log":
+				return &s3.HeadObjectOutput{}, nil
+			case "secret":
+				return nil, errors.New("forbidden")
+			}
+			return nil, &types.NotFound{}
+		},
+	}
+
+	if ok, err := isS3Object(context.TODO(), mockClient, "test-bucket", "logs/app.log"); !ok || err != nil {
+		t.Errorf("expected logs/app.log to be an object, got %v (%v)", ok, err)
+	}
+	if ok, err := isS3Object(context.TODO(), mockClient, "test-bucket", "logs"); ok || err != nil {
+		t.Errorf("expected logs not to be an object, got %v (%v)", ok, err)
+	}
+	if _, err := isS3Object(context.TODO(), mockClient, "test-bucket", "secret"); err == nil {
+		t.Error("expected other errors to be returned")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// entryBaseName returns the last path segment of an entry's key, without the
+// trailing slash of directories
+func entryBaseName(key string) string {
+	return path.Base(strings.TrimSuffix(key, "/"))
+}
+
+// parentPrefix returns the prefix containing the entry with the given key,
+// e.g. "a/b/" for both "a/b/c.txt" and "a/b/c/"
+func parentPrefix(key string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(key, "/"), entryBaseName(key))
+}
+
+// validateEntryName checks that name can be used as the last path segment
+// of a new or renamed entry
+func validateEntryName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	return nil
+}
+
+// markByGlob marks (or, if mark is false, unmarks) every entry whose base
+// name matches the glob pattern. It returns the number of matching entries.
+func markByGlob(entries []ObjectEntry, marked map[string]bool, pattern string, mark bool) (int, error) {
+	// Validate the pattern up front so a bad pattern is reported even when
+	// there are no entries to match against
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, err
+	}
+
+	matches := 0
+	for _, entry := range entries {
+		if ok, _ := path.Match(pattern, entryBaseName(entry.Key)); !ok {
+			continue
+		}
+		matches++
+		if mark {
+			marked[entry.Key] = true
+		} else {
+			delete(marked, entry.Key)
+		}
+	}
+	return matches, nil
+}
+
+// invertMarks toggles the mark of every entry
+func invertMarks(entries []ObjectEntry, marked map[string]bool) {
+	for _, entry := range entries {
+		if marked[entry.Key] {
+			delete(marked, entry.Key)
+		} else {
+			marked[entry.Key] = true
+		}
+	}
+}
+
+// markedEntries returns the marked entries in table order
+func markedEntries(entries []ObjectEntry, marked map[string]bool) []ObjectEntry {
+	var result []ObjectEntry
+	for _, entry := range entries {
+		if marked[entry.Key] {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEntryBaseName(t *testing.T) {
+	tests := map[string]string{
+		"logs/2024/app.log": "app.log",
+		"logs/2024/":        "2024",
+		"file.txt":          "file.txt",
+	}
+
+	for key, expected := range tests {
+		if result := entryBaseName(key); result != expected {
+			t.Errorf("entryBaseName(%q) = %q, expected %q", key, result, expected)
+		}
+	}
+}
+
+func TestParentPrefix(t *testing.T) {
+	tests := map[string]string{
+		"logs/2024/app.log": "logs/2024/",
+		"logs/2024/":        "logs/",
+		"file.txt":          "",
+	}
+
+	for key, expected := range tests {
+		if result := parentPrefix(key); result != expected {
+			t.Errorf("parentPrefix(%q) = %q, expected %q", key, result, expected)
+		}
+	}
+}
+
+func TestMarkByGlob(t *testing.T) {
+	entries := []ObjectEntry{
+		{Key: "logs/app.log"},
+		{Key: "logs/db.log"},
+		{Key: "logs/readme.txt"},
+		{Key: "logs/archive/", IsDirectory: true},
+	}
+	marked := make(map[string]bool)
+
+	matches, err := markByGlob(entries, marked, "*.log", true)
+	if err != nil {
+		t.Fatalf("markByGlob returned an error: %v", err)
+	}
+	if matches != 2 || !marked["logs/app.log"] || !marked["logs/db.log"] {
+		t.Errorf("expected both .log files to be marked, got %v", marked)
+	}
+
+	if _, err := markByGlob(entries, marked, "arch*", true); err != nil {
+		t.Fatalf("markByGlob returned an error: %v", err)
+	}
+	if !marked["logs/archive/"] {
+		t.Error("expected directory to be matched by its name without the trailing slash")
+	}
+
+	if _, err := markByGlob(entries, marked, "db.*", false); err != nil {
+		t.Fatalf("markByGlob returned an error: %v", err)
+	}
+	if marked["logs/db.log"] {
+		t.Error("expected 'logs/db.log' to be unmarked")
+	}
+
+	if _, err := markByGlob(entries, marked, "[", true); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+func TestInvertAndMarkedEntries(t *testing.T) {
+	entries := []ObjectEntry{{Key: "a"}, {Key: "b"}, {Key: "c"}}
+	marked := map[string]bool{"b": true}
+
+	invertMarks(entries, marked)
+
+	result := markedEntries(entries, marked)
+	if len(result) != 2 || result[0].Key != "a" || result[1].Key != "c" {
+		t.Errorf("expected 'a' and 'c' to be marked in table order, got %v", result)
+	}
+}
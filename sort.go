@@ -0,0 +1,145 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// sortField is the column the object table is sorted by
+type sortField string
+
+const (
+	sortByName         sortField = "name"
+	sortBySize         sortField = "size"
+	sortByModified     sortField = "modified"
+	sortByStorageClass sortField = "storage-class"
+)
+
+// sortFields lists the fields in the order 's' cycles through them
+var sortFields = []sortField{sortByName, sortBySize, sortByModified, sortByStorageClass}
+
+// next returns the field after f, wrapping around. Unknown fields, e.g. from
+// an old state file, continue with the first one.
+func (f sortField) next() sortField {
+	for i, field := range sortFields {
+		if field == f {
+			return sortFields[(i+1)%len(sortFields)]
+		}
+	}
+	return sortFields[0]
+}
+
+// column returns the table column showing f
+func (f sortField) column() int {
+	switch f {
+	case sortBySize:
+		return 1
+	case sortByModified:
+		return 2
+	case sortByStorageClass:
+		return 3
+	}
+	return 0
+}
+
+// entrySort is the order of the object table
+type entrySort struct {
+	field      sortField
+	descending bool
+}
+
+// indicator returns the arrow shown in the header of the sorted column
+func (s entrySort) indicator() string {
+	if s.descending {
+		return "▼"
+	}
+	return "▲"
+}
+
+// compareEntries orders two files or two directories by field, returning a
+// negative number if a comes first. Directories have no size, date or
+// storage class, so they are compared by name only.
+func compareEntries(a, b ObjectEntry, field sortField) int {
+	if !a.IsDirectory {
+		switch field {
+		case sortBySize:
+			switch {
+			case a.Size < b.Size:
+				return -1
+			case a.Size > b.Size:
+				return 1
+			}
+		case sortByModified:
+			switch {
+			case a.LastModified == nil || b.LastModified == nil:
+				if a.LastModified != nil {
+					return 1
+				}
+				if b.LastModified != nil {
+					return -1
+				}
+			case a.LastModified.Before(*b.LastModified):
+				return -1
+			case a.LastModified.After(*b.LastModified):
+				return 1
+			}
+		case sortByStorageClass:
+			if c := strings.Compare(a.StorageClass, b.StorageClass); c != 0 {
+				return c
+			}
+		}
+	}
+	return strings.Compare(a.Key, b.Key)
+}
+
+// isListingOrder reports whether the order is the one S3 lists keys in, by
+// name ascending, so that later pages mostly add entries at the end
+func (s entrySort) isListingOrder() bool {
+	return s.field == sortByName && !s.descending
+}
+
+// less reports whether a comes before b. Directories always come before
+// files; the direction applies within each group.
+func (s entrySort) less(a, b ObjectEntry) bool {
+	if a.IsDirectory != b.IsDirectory {
+		return a.IsDirectory
+	}
+	c := compareEntries(a, b, s.field)
+	if s.descending {
+		return c > 0
+	}
+	return c < 0
+}
+
+// sortEntries sorts entries in place
+func sortEntries(entries []ObjectEntry, order entrySort) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return order.less(entries[i], entries[j])
+	})
+}
+
+// entryPosition returns the index at which entry goes into the sorted
+// entries, after any equal ones
+func entryPosition(entries []ObjectEntry, entry ObjectEntry, order entrySort) int {
+	return sort.Search(len(entries), func(i int) bool {
+		return order.less(entry, entries[i])
+	})
+}
+
+// mergeEntries merges the sorted entries of a new page into the sorted
+// entries loaded before, which come first among equal ones
+func mergeEntries(entries, page []ObjectEntry, order entrySort) []ObjectEntry {
+	merged := make([]ObjectEntry, 0, len(entries)+len(page))
+	i, j := 0, 0
+	for i < len(entries) && j < len(page) {
+		if order.less(page[j], entries[i]) {
+			merged = append(merged, page[j])
+			j++
+		} else {
+			merged = append(merged, entries[i])
+			i++
+		}
+	}
+	merged = append(merged, entries[i:]...)
+	return append(merged, page[j:]...)
+}
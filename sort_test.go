@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortFieldNext(t *testing.T) {
+	field := sortByName
+	var seen []string
+	for i := 0; i < len(sortFields); i++ {
+		field = field.next()
+		seen = append(seen, string(field))
+	}
+	if strings.Join(seen, ",") != "size,modified,storage-class,name" {
+		t.Errorf("unexpected cycle %v", seen)
+	}
+	if next := sortField("unknown").next(); next != sortByName {
+		t.Errorf("expected an unknown field to continue with name, got %s", next)
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	entries := []ObjectEntry{
+		{Key: "b.txt", Size: 30, LastModified: day(1), StorageClass: "STANDARD"},
+		{Key: "logs/", IsDirectory: true},
+		{Key: "a.txt", Size: 10, LastModified: day(3), StorageClass: "GLACIER"},
+		{Key: "c.txt", Size: 20, StorageClass: "STANDARD"},
+		{Key: "archive/", IsDirectory: true},
+	}
+
+	tests := []struct {
+		order    entrySort
+		expected string
+	}{
+		{entrySort{field: sortByName}, "archive/,logs/,a.txt,b.txt,c.txt"},
+		{entrySort{field: sortByName, descending: true}, "logs/,archive/,c.txt,b.txt,a.txt"},
+		{entrySort{field: sortBySize}, "archive/,logs/,a.txt,c.txt,b.txt"},
+		{entrySort{field: sortBySize, descending: true}, "logs/,archive/,b.txt,c.txt,a.txt"},
+		{entrySort{field: sortByModified}, "archive/,logs/,c.txt,b.txt,a.txt"},
+		{entrySort{field: sortByStorageClass}, "archive/,logs/,a.txt,b.txt,c.txt"},
+		{entrySort{field: sortByStorageClass, descending: true}, "logs/,archive/,c.txt,b.txt,a.txt"},
+	}
+	for _, tt := range tests {
+		sortEntries(entries, tt.order)
+		var keys []string
+		for _, entry := range entries {
+			keys = append(keys, entry.Key)
+		}
+		if result := strings.Join(keys, ","); result != tt.expected {
+			t.Errorf("%+v: expected %s, got %s", tt.order, tt.expected, result)
+		}
+	}
+}
+
+func TestMergeEntries(t *testing.T) {
+	order := entrySort{field: sortBySize}
+	loaded := []ObjectEntry{
+		{Key: "logs/", IsDirectory: true},
+		{Key: "a.txt", Size: 10},
+		{Key: "c.txt", Size: 30},
+	}
+	page := []ObjectEntry{
+		{Key: "archive/", IsDirectory: true},
+		{Key: "b.txt", Size: 20},
+		{Key: "d.txt", Size: 40},
+	}
+
+	var keys []string
+	for _, entry := range mergeEntries(loaded, page, order) {
+		keys = append(keys, entry.Key)
+	}
+	if got := strings.Join(keys, ","); got != "archive/,logs/,a.txt,b.txt,c.txt,d.txt" {
+		t.Errorf("unexpected merge order %s", got)
+	}
+}
+
+func TestEntryPosition(t *testing.T) {
+	order := entrySort{field: sortByName}
+	entries := []ObjectEntry{
+		{Key: "logs/", IsDirectory: true},
+		{Key: "a.txt"},
+		{Key: "c.txt"},
+	}
+
+	tests := []struct {
+		entry    ObjectEntry
+		expected int
+	}{
+		{ObjectEntry{Key: "archive/", IsDirectory: true}, 0},
+		{ObjectEntry{Key: "zz/", IsDirectory: true}, 1},
+		{ObjectEntry{Key: "b.txt"}, 2},
+		{ObjectEntry{Key: "d.txt"}, 3},
+	}
+	for _, tt := range tests {
+		if got := entryPosition(entries, tt.entry, order); got != tt.expected {
+			t.Errorf("entryPosition(%s) = %d, want %d", tt.entry.Key, got, tt.expected)
+		}
+	}
+	if !order.isListingOrder() || (entrySort{field: sortByName, descending: true}).isListingOrder() {
+		t.Errorf("expected only name ascending to be the listing order")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// maxTabLabel limits the width of a path in the tab bar
+const maxTabLabel = 40
+
+// TabState is an open tab as saved in the state file: the S3 location it
+// shows, or the bucket list if Bucket is empty, and the selected entry
+type TabState struct {
+	Bucket      string `json:"bucket"`
+	Prefix      string `json:"prefix"`
+	SelectedKey string `json:"selected_key"`
+}
+
+// browserTab holds the panes and layout of a tab while another tab is
+// active. Each pane has its own history.
+type browserTab struct {
+	panes      [2]paneState
+	activePane int
+	dualPane   bool
+}
+
+// newBrowserTab creates a tab that has not been shown yet. It opens at the
+// location of state when it is first shown.
+func newBrowserTab(state TabState) *browserTab {
+	history := newNavHistory()
+	history.visit(state.Bucket, state.Prefix)
+	history.setSelected(state.Bucket, state.Prefix, state.SelectedKey)
+	tab := &browserTab{}
+	tab.panes[0].history = history
+	return tab
+}
+
+// shown reports whether the tab has been shown since it was created
+func (t *browserTab) shown() bool {
+	return t.panes[t.activePane].view != nil
+}
+
+// state returns the tab's S3 location to save. A tab browsing the local
+// filesystem is saved at the last S3 location it showed.
+func (t *browserTab) state() TabState {
+	var state TabState
+	pane := t.panes[t.activePane]
+	if pane.history != nil {
+		if loc, ok := pane.history.current(); ok {
+			state = TabState{Bucket: loc.bucket, Prefix: loc.prefix, SelectedKey: loc.selected}
+		}
+	}
+	if t.shown() && pane.local == nil && (pane.bucket != state.Bucket || pane.prefix != state.Prefix) {
+		state = TabState{Bucket: pane.bucket, Prefix: pane.prefix}
+	}
+	return state
+}
+
+// label returns the path the tab shows in its active pane
+func (t *browserTab) label() string {
+	if pane := t.panes[t.activePane]; pane.local != nil {
+		return pane.local.dir
+	}
+	state := t.state()
+	if state.Bucket == "" {
+		return "s3://"
+	}
+	return fmt.Sprintf("s3://%s/%s", state.Bucket, state.Prefix)
+}
+
+// formatTabBar renders the tab bar, highlighting the current tab. Long
+// labels are shortened from the start, as the innermost folder matters most.
+func formatTabBar(labels []string, current int) string {
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		if runes := []rune(label); len(runes) > maxTabLabel {
+			label = "…" + string(runes[len(runes)-maxTabLabel+1:])
+		}
+		text := tview.Escape(fmt.Sprintf(" %d: %s ", i+1, label))
+		if i == current {
+			parts[i] = "[black:yellow]" + text + "[-:-]"
+		} else {
+			parts[i] = "[white]" + text + "[-]"
+		}
+	}
+	return strings.Join(parts, "[gray]│[-]")
+}
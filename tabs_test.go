@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestBrowserTabState(t *testing.T) {
+	state := TabState{Bucket: "my-bucket", Prefix: "logs/", SelectedKey: "logs/app.log"}
+	tab := newBrowserTab(state)
+	if tab.shown() {
+		t.Errorf("Expected a new tab not to be shown")
+	}
+	if got := tab.state(); got != state {
+		t.Errorf("Expected state %+v, got %+v", state, got)
+	}
+	if got := tab.label(); got != "s3://my-bucket/logs/" {
+		t.Errorf("Expected label s3://my-bucket/logs/, got %s", got)
+	}
+
+	// A shown tab is saved at the location of its active pane
+	tab.panes[0] = paneState{view: tview.NewBox(), bucket: "other", prefix: "data/"}
+	if got := tab.state(); got != (TabState{Bucket: "other", Prefix: "data/"}) {
+		t.Errorf("Expected the active pane's location, got %+v", got)
+	}
+
+	if got := newBrowserTab(TabState{}).label(); got != "s3://" {
+		t.Errorf("Expected the bucket list label s3://, got %s", got)
+	}
+}
+
+func TestFormatTabBar(t *testing.T) {
+	bar := formatTabBar([]string{"s3://a/", "s3://b/c/"}, 1)
+	if !strings.Contains(bar, "[white] 1: s3://a/ [-]") {
+		t.Errorf("Expected the first tab to be plain, got %q", bar)
+	}
+	if !strings.Contains(bar, "[black:yellow] 2: s3://b/c/ [-:-]") {
+		t.Errorf("Expected the second tab to be highlighted, got %q", bar)
+	}
+
+	long := "s3://bucket/" + strings.Repeat("x", 60) + "/inner/"
+	bar = formatTabBar([]string{long}, 0)
+	if !strings.Contains(bar, "…") || !strings.Contains(bar, "/inner/ ") {
+		t.Errorf("Expected the long label to be shortened from the start, got %q", bar)
+	}
+	if strings.Contains(bar, "s3://bucket") {
+		t.Errorf("Expected the start of the long label to be cut, got %q", bar)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Objects of at least rangedDownloadThreshold bytes are downloaded as
+// rangedChunkSize byte ranges fetched by rangedWorkers concurrent requests
+var (
+	rangedDownloadThreshold int64 = 64 * 1024 * 1024
+	rangedChunkSize         int64 = 8 * 1024 * 1024
+)
+
+const rangedWorkers = 4
+
+// manifestSuffix is appended to the local path for the sidecar file that
+// records which ranges of a partial download are complete
+const manifestSuffix = ".part.json"
+
+// downloadManifest describes a partial ranged download so that it can be
+// resumed. It is only valid for the exact object version it was created for.
+type downloadManifest struct {
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	ETag      string `json:"etag"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	Completed []bool `json:"completed"`
+}
+
+// chunkCount returns the number of ranges the object is split into
+func (m *downloadManifest) chunkCount() int {
+	return int((m.Size + m.ChunkSize - 1) / m.ChunkSize)
+}
+
+// chunkRange returns the inclusive byte range of chunk i
+func (m *downloadManifest) chunkRange(i int) (start, end int64) {
+	start = int64(i) * m.ChunkSize
+	end = start + m.ChunkSize - 1
+	if end >= m.Size {
+		end = m.Size - 1
+	}
+	return start, end
+}
+
+// completedBytes returns the number of bytes in completed chunks
+func (m *downloadManifest) completedBytes() int64 {
+	var total int64
+	for i, done := range m.Completed {
+		if done {
+			start, end := m.chunkRange(i)
+			total += end - start + 1
+		}
+	}
+	return total
+}
+
+// matches reports whether the manifest was written for the same object version
+func (m *downloadManifest) matches(other *downloadManifest) bool {
+	return m.Bucket == other.Bucket && m.Key == other.Key && m.ETag == other.ETag &&
+		m.Size == other.Size && m.ChunkSize == other.ChunkSize && len(m.Completed) == other.chunkCount()
+}
+
+// loadManifest reads the manifest at path
+func loadManifest(path string) (*downloadManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest downloadManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// save writes the manifest to path, replacing it atomically
+func (m *downloadManifest) save(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// multipartETagMD5 computes the ETag S3 assigns to an object uploaded in
+// parts of partSize bytes: the MD5 of the concatenated part MD5s, followed by
+// the number of parts
+func multipartETagMD5(path string, partSize int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var digests []byte
+	parts := 0
+	for {
+		hash := md5.New()
+		n, err := io.CopyN(hash, file, partSize)
+		if n > 0 {
+			digests = append(digests, hash.Sum(nil)...)
+			parts++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	sum := md5.Sum(digests)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), parts), nil
+}
+
+// errChecksumMismatch is returned by verifyDownload when the downloaded
+// content does not match the object's ETag
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// verifyDownload checks the downloaded file against the object's ETag, when
+// the ETag is known to be an MD5 of the content. For single part uploads the
+// ETag is the MD5 of the content. For multipart uploads it is derived from
+// the MD5s of the parts; the part size is looked up with a HeadObject request
+// for part 1, assuming all parts but the last have the same size. The check
+// is skipped for objects encrypted with SSE-KMS or SSE-C, whose ETag is not
+// an MD5, and for multipart uploads whose part count shows that the parts
+// were not the same size.
+func verifyDownload(ctx context.Context, client S3Client, bucketName, key, path, etag string) error {
+	etag = strings.Trim(etag, `"`)
+	if etag == "" {
+		return nil
+	}
+
+	multipart := strings.Contains(etag, "-")
+	input := &s3.HeadObjectInput{
+		Bucket: &bucketName,
+		Key:    &key,
+	}
+	if multipart {
+		input.PartNumber = aws.Int32(1)
+	}
+	head, err := client.HeadObject(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to look up object for verification: %w", err)
+	}
+	if head.SSECustomerAlgorithm != nil ||
+		head.ServerSideEncryption == types.ServerSideEncryptionAwsKms ||
+		head.ServerSideEncryption == types.ServerSideEncryptionAwsKmsDsse {
+		return nil
+	}
+
+	var actual string
+	if !multipart {
+		actual, err = fileMD5(path)
+	} else {
+		if head.ContentLength == nil || *head.ContentLength <= 0 {
+			return nil
+		}
+		actual, err = multipartETagMD5(path, *head.ContentLength)
+		if err == nil && head.PartsCount != nil && !strings.HasSuffix(actual, fmt.Sprintf("-%d", *head.PartsCount)) {
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to checksum download: %w", err)
+	}
+
+	if actual != etag {
+		return fmt.Errorf("%w: expected ETag %s, got %s", errChecksumMismatch, etag, actual)
+	}
+	return nil
+}
+
+// downloadRanged downloads a large object to localPath by fetching byte
+// ranges concurrently into localPath+partialSuffix. Completed ranges are
+// recorded in a sidecar manifest so that an interrupted download resumes
+// where it left off, provided the object's ETag and size are unchanged.
+// Every range request is conditional on the ETag, so a concurrent overwrite
+// of the object fails the download instead of mixing two versions. Once all
+// ranges are written the file is verified against the ETag and moved into
+// place. On failure or cancellation the partial file and manifest are kept
+// for resuming if keepPartial is set or the transfer was paused, and removed
+// otherwise. onProgress is called with the running total, one call at a
+// time, so the totals it sees never go backwards.
+func downloadRanged(ctx context.Context, client S3Client, bucketName, key, localPath string, size int64, etag string, keepPartial bool, onProgress func(current, total int64)) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create local directory: %w", err)
+	}
+
+	partialPath := localPath + partialSuffix
+	manifestPath := localPath + manifestSuffix
+
+	manifest := &downloadManifest{
+		Bucket:    bucketName,
+		Key:       key,
+		ETag:      etag,
+		Size:      size,
+		ChunkSize: rangedChunkSize,
+	}
+	manifest.Completed = make([]bool, manifest.chunkCount())
+
+	// Resume from an existing manifest if it belongs to this object version
+	// and the partial file is still there
+	if previous, err := loadManifest(manifestPath); err == nil && previous.matches(manifest) {
+		if info, err := os.Stat(partialPath); err == nil && info.Size() == size {
+			manifest = previous
+		}
+	}
+
+	file, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create local file: %w", err)
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to allocate local file: %w", err)
+	}
+
+	cleanup := func() {
+		if !keepPartial && !transferPaused(ctx) {
+			os.Remove(partialPath)
+			os.Remove(manifestPath)
+		}
+	}
+
+	// The chunk workers report concurrently; the lock keeps the calls to
+	// onProgress in the order the total was updated
+	var progressMu sync.Mutex
+	transferred := manifest.completedBytes()
+	report := func(delta int64) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		transferred += delta
+		if onProgress != nil {
+			onProgress(transferred, size)
+		}
+	}
+	report(0)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	chunks := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < rangedWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				err := downloadChunk(ctx, client, manifest, chunk, file, report)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					cancel()
+				} else {
+					manifest.Completed[chunk] = true
+					if saveErr := manifest.save(manifestPath); saveErr != nil && firstErr == nil {
+						firstErr = fmt.Errorf("failed to save download manifest: %w", saveErr)
+						cancel()
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i, done := range manifest.Completed {
+		if done {
+			continue
+		}
+		select {
+		case chunks <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	if closeErr := file.Close(); firstErr == nil {
+		firstErr = closeErr
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		cleanup()
+		return firstErr
+	}
+
+	if err := verifyDownload(ctx, client, bucketName, key, partialPath, etag); err != nil {
+		// The content is wrong, so resuming from it would be pointless. If it
+		// could not be checked, keep it so that retrying checks it again.
+		if errors.Is(err, errChecksumMismatch) {
+			os.Remove(partialPath)
+			os.Remove(manifestPath)
+		}
+		return err
+	}
+
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return fmt.Errorf("failed to move downloaded file into place: %w", err)
+	}
+	os.Remove(manifestPath)
+
+	return nil
+}
+
+// downloadChunk fetches one byte range of the object and writes it at its
+// offset in file
+func downloadChunk(ctx context.Context, client S3Client, manifest *downloadManifest, chunk int, file *os.File, report func(delta int64)) error {
+	start, end := manifest.chunkRange(chunk)
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(manifest.Bucket),
+		Key:    aws.String(manifest.Key),
+		Range:  aws.String("bytes=" + strconv.FormatInt(start, 10) + "-" + strconv.FormatInt(end, 10)),
+	}
+	if manifest.ETag != "" {
+		input.IfMatch = aws.String(manifest.ETag)
+	}
+
+	resp, err := client.GetObject(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to get bytes %d-%d: %w", start, end, err)
+	}
+	defer resp.Body.Close()
+
+	// Count progress as it is written, and take it back if the chunk fails
+	// so the total stays accurate for the retry
+	var written int64
+	reader := &ProgressReader{
+		reader: &contextReader{ctx: ctx, reader: resp.Body},
+		onProgress: func(current, total int64) {
+			report(current - written)
+			written = current
+		},
+	}
+
+	n, err := io.Copy(io.NewOffsetWriter(file, start), io.LimitReader(reader, end-start+1))
+	if err == nil && n != end-start+1 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		report(-written)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to write bytes %d-%d: %w", start, end, err)
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Causes attached to a job's context when it is stopped from the queue, so
+// transfers can tell a pause (keep partial data to resume) from a cancel
+var (
+	errTransferPaused    = errors.New("transfer paused")
+	errTransferCancelled = errors.New("transfer cancelled")
+)
+
+// transferPaused reports whether ctx was cancelled to pause its transfer
+func transferPaused(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errTransferPaused)
+}
+
+// transferState is the lifecycle state of a queued transfer
+type transferState int
+
+const (
+	transferQueued transferState = iota
+	transferRunning
+	transferPausedState
+	transferDone
+	transferFailed
+	transferCancelled
+)
+
+func (s transferState) String() string {
+	switch s {
+	case transferQueued:
+		return "Queued"
+	case transferRunning:
+		return "Running"
+	case transferPausedState:
+		return "Paused"
+	case transferDone:
+		return "Done"
+	case transferFailed:
+		return "Failed"
+	case transferCancelled:
+		return "Cancelled"
+	}
+	return "Unknown"
+}
+
+// finished reports whether the state is final until the job is retried
+func (s transferState) finished() bool {
+	return s == transferDone || s == transferFailed || s == transferCancelled
+}
+
+// rateWindow is how far back transfer rates are averaged. Progress reported
+// more often than rateSampleInterval is merged into a single sample.
+const (
+	rateWindow         = 5 * time.Second
+	rateSampleInterval = 100 * time.Millisecond
+)
+
+// rateSample is the number of bytes transferred at a point in time
+type rateSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// rateMeter computes a rolling transfer rate over rateWindow
+type rateMeter struct {
+	samples []rateSample
+}
+
+// add records the total bytes transferred at time now
+func (r *rateMeter) add(now time.Time, bytes int64) {
+	sample := rateSample{at: now, bytes: bytes}
+	if n := len(r.samples); n >= 2 && now.Sub(r.samples[n-2].at) < rateSampleInterval {
+		r.samples[n-1] = sample
+		return
+	}
+	r.samples = append(r.samples, sample)
+
+	// Drop samples that fell out of the window, keeping one just outside it
+	// so the rate covers the whole window
+	drop := 0
+	for drop < len(r.samples)-2 && now.Sub(r.samples[drop+1].at) >= rateWindow {
+		drop++
+	}
+	r.samples = r.samples[drop:]
+}
+
+// reset forgets all samples
+func (r *rateMeter) reset() {
+	r.samples = nil
+}
+
+// rate returns the average bytes per second over the window
+func (r *rateMeter) rate() float64 {
+	if len(r.samples) < 2 {
+		return 0
+	}
+	first, last := r.samples[0], r.samples[len(r.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.bytes-first.bytes) / elapsed
+}
+
+// estimateRemaining returns the time left to transfer the remaining bytes at
+// rate bytes per second, or zero if it cannot be estimated
+func estimateRemaining(current, total int64, rate float64) time.Duration {
+	if rate <= 0 || total <= 0 || current >= total {
+		return 0
+	}
+	return time.Duration(float64(total-current) / rate * float64(time.Second))
+}
+
+// transferFunc performs a transfer, calling report with its progress and an
+// optional detail line (e.g. files done). It must stop when ctx is cancelled.
+type transferFunc func(ctx context.Context, report func(current, total int64, detail string)) error
+
+// transferJob is a transfer in the queue. All fields are guarded by the
+// queue's mutex.
+type transferJob struct {
+	id        int
+	action    string
+	name      string
+	resumable bool // whether a paused run continues where it stopped
+	run       transferFunc
+	onFinish  func(info transferInfo)
+
+	state     transferState
+	current   int64
+	total     int64
+	detail    string
+	err       error
+	startedAt time.Time
+	elapsed   time.Duration
+	meter     rateMeter
+	cancel    context.CancelCauseFunc
+}
+
+// transferInfo is a snapshot of a job for display
+type transferInfo struct {
+	ID        int
+	Action    string
+	Name      string
+	Resumable bool
+	State     transferState
+	Current   int64
+	Total     int64
+	Detail    string
+	Err       error
+	Rate      float64
+	ETA       time.Duration
+	Elapsed   time.Duration
+}
+
+// StateName names the job's state. A job that cannot resume is stopped
+// rather than paused, as it starts over when resumed.
+func (info transferInfo) StateName() string {
+	if info.State == transferPausedState && !info.Resumable {
+		return "Stopped"
+	}
+	return info.State.String()
+}
+
+// defaultTransferConcurrency is the number of transfers run at once unless
+// configured otherwise
+const defaultTransferConcurrency = 3
+
+// TransferQueue runs transfers in the background, at most concurrency at a time
+type TransferQueue struct {
+	mu          sync.Mutex
+	jobs        []*transferJob
+	concurrency int
+	running     int
+	nextID      int
+}
+
+// NewTransferQueue creates a queue running up to concurrency transfers at once
+func NewTransferQueue(concurrency int) *TransferQueue {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &TransferQueue{concurrency: concurrency}
+}
+
+// Add queues a transfer. resumable tells whether run continues where a
+// paused run stopped; otherwise pausing the job stops it and it starts over
+// when resumed. onFinish, if not nil, is called each time the job stops
+// (done, failed, paused or cancelled), also when it is paused or cancelled
+// before it ran. It is called from a goroutine of the queue, never the
+// caller's.
+func (q *TransferQueue) Add(action, name string, resumable bool, run transferFunc, onFinish func(info transferInfo)) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.nextID++
+	q.jobs = append(q.jobs, &transferJob{
+		id:        q.nextID,
+		action:    action,
+		name:      name,
+		resumable: resumable,
+		run:       run,
+		onFinish:  onFinish,
+		state:     transferQueued,
+	})
+	q.schedule()
+	return q.nextID
+}
+
+// Concurrency returns the maximum number of transfers running at once
+func (q *TransferQueue) Concurrency() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.concurrency
+}
+
+// SetConcurrency changes the maximum number of transfers running at once.
+// Running transfers are not interrupted when it is lowered.
+func (q *TransferQueue) SetConcurrency(concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.concurrency = concurrency
+	q.schedule()
+}
+
+// schedule starts queued jobs while there is capacity. Callers hold q.mu.
+func (q *TransferQueue) schedule() {
+	for _, job := range q.jobs {
+		if q.running >= q.concurrency {
+			return
+		}
+		if job.state == transferQueued {
+			q.start(job)
+		}
+	}
+}
+
+// start runs job in a new goroutine. Callers hold q.mu.
+func (q *TransferQueue) start(job *transferJob) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	job.state = transferRunning
+	job.cancel = cancel
+	job.err = nil
+	job.startedAt = time.Now()
+	job.meter.reset()
+	q.running++
+
+	report := func(current, total int64, detail string) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		job.current = current
+		job.total = total
+		job.detail = detail
+		job.meter.add(time.Now(), current)
+	}
+
+	go func() {
+		err := job.run(ctx, report)
+
+		q.mu.Lock()
+		q.running--
+		job.elapsed += time.Since(job.startedAt)
+		switch cause := context.Cause(ctx); {
+		case errors.Is(cause, errTransferPaused):
+			job.state = transferPausedState
+		case errors.Is(cause, errTransferCancelled):
+			job.state = transferCancelled
+		case err != nil:
+			job.state = transferFailed
+			job.err = err
+		default:
+			job.state = transferDone
+		}
+		cancel(nil)
+		job.cancel = nil
+		info := q.info(job)
+		q.schedule()
+		q.mu.Unlock()
+
+		if job.onFinish != nil {
+			job.onFinish(info)
+		}
+	}()
+}
+
+// find returns the job with the given id. Callers hold q.mu.
+func (q *TransferQueue) find(id int) *transferJob {
+	for _, job := range q.jobs {
+		if job.id == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// Pause stops a running or queued job. A resumable job keeps its partial
+// data so that Resume can continue it; any other starts over.
+func (q *TransferQueue) Pause(id int) {
+	q.mu.Lock()
+	job := q.find(id)
+	switch {
+	case job == nil:
+	case job.state == transferRunning:
+		job.cancel(errTransferPaused)
+	case job.state == transferQueued:
+		job.state = transferPausedState
+		q.stopped(job)
+		return
+	}
+	q.mu.Unlock()
+}
+
+// Resume queues a paused job again
+func (q *TransferQueue) Resume(id int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if job := q.find(id); job != nil && job.state == transferPausedState {
+		if !job.resumable {
+			job.resetProgress()
+		}
+		job.state = transferQueued
+		q.schedule()
+	}
+}
+
+// Retry queues a failed or cancelled job again, from the start
+func (q *TransferQueue) Retry(id int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if job := q.find(id); job != nil && (job.state == transferFailed || job.state == transferCancelled) {
+		job.resetProgress()
+		job.state = transferQueued
+		job.err = nil
+		q.schedule()
+	}
+}
+
+// resetProgress forgets the progress of a job that starts over. Callers hold
+// the queue's mutex.
+func (job *transferJob) resetProgress() {
+	job.current = 0
+	job.detail = ""
+}
+
+// Cancel stops a job for good
+func (q *TransferQueue) Cancel(id int) {
+	q.mu.Lock()
+	job := q.find(id)
+	switch {
+	case job == nil:
+	case job.state == transferRunning:
+		job.cancel(errTransferCancelled)
+	case job.state == transferQueued || job.state == transferPausedState:
+		job.state = transferCancelled
+		q.stopped(job)
+		return
+	}
+	q.mu.Unlock()
+}
+
+// stopped reports a job that was stopped without running, as its goroutine
+// would have done had it been running. Callers hold q.mu, which is released.
+// onFinish runs in a goroutine of its own, as callers may be on the UI
+// goroutine where a handler queuing a redraw would deadlock.
+func (q *TransferQueue) stopped(job *transferJob) {
+	info := q.info(job)
+	q.mu.Unlock()
+
+	if job.onFinish != nil {
+		go job.onFinish(info)
+	}
+}
+
+// ClearFinished removes jobs that are done or cancelled
+func (q *TransferQueue) ClearFinished() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := q.jobs[:0]
+	for _, job := range q.jobs {
+		if job.state != transferDone && job.state != transferCancelled {
+			jobs = append(jobs, job)
+		}
+	}
+	q.jobs = jobs
+}
+
+// info builds a snapshot of job. Callers hold q.mu.
+func (q *TransferQueue) info(job *transferJob) transferInfo {
+	info := transferInfo{
+		ID:        job.id,
+		Action:    job.action,
+		Name:      job.name,
+		Resumable: job.resumable,
+		State:     job.state,
+		Current:   job.current,
+		Total:     job.total,
+		Detail:    job.detail,
+		Err:       job.err,
+		Elapsed:   job.elapsed,
+	}
+	if job.state == transferRunning {
+		info.Elapsed += time.Since(job.startedAt)
+		info.Rate = job.meter.rate()
+		info.ETA = estimateRemaining(job.current, job.total, info.Rate)
+	}
+	return info
+}
+
+// Jobs returns a snapshot of all jobs in the order they were added
+func (q *TransferQueue) Jobs() []transferInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	infos := make([]transferInfo, len(q.jobs))
+	for i, job := range q.jobs {
+		infos[i] = q.info(job)
+	}
+	return infos
+}
+
+// Job returns a snapshot of the job with the given id
+func (q *TransferQueue) Job(id int) (transferInfo, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if job := q.find(id); job != nil {
+		return q.info(job), true
+	}
+	return transferInfo{}, false
+}
+
+// transferBatch counts the outcomes of a batch of jobs queued together, so
+// that the batch can be reported once all of them have finished. A retried
+// job finishes again, so what counts is the last outcome of each.
+type transferBatch struct {
+	mu       sync.Mutex
+	size     int
+	outcomes map[int]transferState
+	counts   map[transferState]int
+}
+
+// newTransferBatch creates a batch of size jobs
+func newTransferBatch(size int) *transferBatch {
+	return &transferBatch{
+		size:     size,
+		outcomes: make(map[int]transferState),
+		counts:   make(map[transferState]int),
+	}
+}
+
+// finish records that job i of the batch finished in state, and returns
+// whether every job has finished and how many of them failed and were
+// cancelled
+func (b *transferBatch) finish(i int, state transferState) (done bool, failed, cancelled int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if previous, ok := b.outcomes[i]; ok {
+		b.counts[previous]--
+	}
+	b.outcomes[i] = state
+	b.counts[state]++
+	return len(b.outcomes) == b.size, b.counts[transferFailed], b.counts[transferCancelled]
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForState polls the queue until job id reaches state, failing the test
+// if it does not within a second
+func waitForState(t *testing.T, queue *TransferQueue, id int, state transferState) transferInfo {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		info, ok := queue.Job(id)
+		if ok && info.State == state {
+			return info
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d: expected state %s, got %s", id, state, info.State)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blockingTransfer returns a transfer that reports progress and then waits
+// for ctx to be cancelled or release to be closed
+func blockingTransfer(release chan struct{}) transferFunc {
+	return func(ctx context.Context, report func(current, total int64, detail string)) error {
+		report(5, 10, "halfway")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-release:
+			report(10, 10, "")
+			return nil
+		}
+	}
+}
+
+func TestRateMeter(t *testing.T) {
+	var meter rateMeter
+	start := time.Now()
+
+	if rate := meter.rate(); rate != 0 {
+		t.Errorf("expected no rate without samples, got %f", rate)
+	}
+
+	meter.add(start, 0)
+	meter.add(start.Add(time.Second), 1000)
+	meter.add(start.Add(2*time.Second), 2000)
+	if rate := meter.rate(); rate != 1000 {
+		t.Errorf("expected 1000 B/s, got %f", rate)
+	}
+
+	// Older samples fall out of the window, so the rate follows the latest speed
+	meter.add(start.Add(10*time.Second), 12000)
+	meter.add(start.Add(11*time.Second), 15000)
+	if rate := meter.rate(); rate < 1250 || rate > 3000 {
+		t.Errorf("expected the rate to reflect recent samples, got %f", rate)
+	}
+
+	if eta := estimateRemaining(500, 1500, 100); eta != 10*time.Second {
+		t.Errorf("expected an ETA of 10s, got %v", eta)
+	}
+	if eta := estimateRemaining(500, 1500, 0); eta != 0 {
+		t.Errorf("expected no ETA without a rate, got %v", eta)
+	}
+}
+
+func TestTransferQueueLifecycle(t *testing.T) {
+	queue := NewTransferQueue(1)
+	release := make(chan struct{})
+
+	finished := make(chan transferInfo, 10)
+	id := queue.Add("Download", "file.txt", true, blockingTransfer(release), func(info transferInfo) {
+		finished <- info
+	})
+
+	info := waitForState(t, queue, id, transferRunning)
+	if info.Name != "file.txt" || info.Action != "Download" {
+		t.Errorf("unexpected job info %+v", info)
+	}
+
+	queue.Pause(id)
+	if info := <-finished; info.State != transferPausedState || info.StateName() != "Paused" {
+		t.Errorf("expected paused, got %s", info.StateName())
+	}
+
+	queue.Resume(id)
+	waitForState(t, queue, id, transferRunning)
+	queue.Cancel(id)
+	if info := <-finished; info.State != transferCancelled {
+		t.Errorf("expected cancelled, got %s", info.State)
+	}
+
+	queue.Retry(id)
+	waitForState(t, queue, id, transferRunning)
+	close(release)
+	if info := <-finished; info.State != transferDone || info.Current != 10 {
+		t.Errorf("expected done with 10 bytes, got %s with %d", info.State, info.Current)
+	}
+
+	queue.ClearFinished()
+	if jobs := queue.Jobs(); len(jobs) != 0 {
+		t.Errorf("expected finished jobs to be cleared, got %d", len(jobs))
+	}
+}
+
+func TestTransferQueueRestart(t *testing.T) {
+	queue := NewTransferQueue(1)
+	started := make(chan int, 10)
+	proceed := make(chan struct{})
+	finished := make(chan transferInfo, 10)
+
+	// The first run reports progress and fails, the second reports progress
+	// once told to, and the third does not report any
+	runs := 0
+	id := queue.Add("Upload", "file.txt", false, func(ctx context.Context, report func(current, total int64, detail string)) error {
+		runs++
+		switch runs {
+		case 1:
+			report(5, 10, "")
+			return errors.New("connection reset")
+		case 2:
+			started <- runs
+			<-proceed
+			report(7, 10, "")
+		}
+		started <- runs
+		<-ctx.Done()
+		return ctx.Err()
+	}, func(info transferInfo) {
+		finished <- info
+	})
+
+	if info := <-finished; info.State != transferFailed || info.Current != 5 {
+		t.Fatalf("expected failed with 5 bytes, got %s with %d", info.State, info.Current)
+	}
+
+	// A retry starts over
+	queue.Retry(id)
+	<-started
+	if info, _ := queue.Job(id); info.Current != 0 {
+		t.Errorf("expected a retry to start from 0 bytes, got %d", info.Current)
+	}
+	proceed <- struct{}{}
+	<-started
+
+	// A job that cannot resume is stopped, and starts over when resumed
+	queue.Pause(id)
+	if info := <-finished; info.StateName() != "Stopped" || info.Current != 7 {
+		t.Errorf("expected stopped with 7 bytes, got %s with %d", info.StateName(), info.Current)
+	}
+	queue.Resume(id)
+	<-started
+	if info, _ := queue.Job(id); info.Current != 0 {
+		t.Errorf("expected a restart to start from 0 bytes, got %d", info.Current)
+	}
+	queue.Cancel(id)
+	<-finished
+}
+
+func TestTransferQueueStopsQueuedJobs(t *testing.T) {
+	queue := NewTransferQueue(1)
+	release := make(chan struct{})
+	defer close(release)
+	queue.Add("Download", "running", true, blockingTransfer(release), nil)
+
+	// Jobs stopped before they ran still report it
+	finished := make(chan transferInfo, 10)
+	onFinish := func(info transferInfo) {
+		finished <- info
+	}
+	paused := queue.Add("Download", "paused", true, blockingTransfer(release), onFinish)
+	cancelled := queue.Add("Upload", "cancelled", false, blockingTransfer(release), onFinish)
+
+	queue.Pause(paused)
+	if info := <-finished; info.ID != paused || info.State != transferPausedState {
+		t.Errorf("expected job %d paused, got job %d %s", paused, info.ID, info.State)
+	}
+	queue.Cancel(cancelled)
+	if info := <-finished; info.ID != cancelled || info.State != transferCancelled {
+		t.Errorf("expected job %d cancelled, got job %d %s", cancelled, info.ID, info.State)
+	}
+	queue.Cancel(paused)
+	if info := <-finished; info.ID != paused || info.State != transferCancelled {
+		t.Errorf("expected job %d cancelled, got job %d %s", paused, info.ID, info.State)
+	}
+}
+
+func TestTransferQueueFailure(t *testing.T) {
+	queue := NewTransferQueue(1)
+	id := queue.Add("Upload", "file.txt", false, func(ctx context.Context, report func(current, total int64, detail string)) error {
+		return errors.New("access denied")
+	}, nil)
+
+	info := waitForState(t, queue, id, transferFailed)
+	if info.Err == nil || info.Err.Error() != "access denied" {
+		t.Errorf("expected the error to be kept, got %v", info.Err)
+	}
+
+	queue.ClearFinished()
+	if jobs := queue.Jobs(); len(jobs) != 1 {
+		t.Errorf("expected failed jobs to be kept for retrying, got %d jobs", len(jobs))
+	}
+}
+
+func TestTransferQueueConcurrency(t *testing.T) {
+	queue := NewTransferQueue(2)
+	release := make(chan struct{})
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		ids = append(ids, queue.Add("Download", "file", true, blockingTransfer(release), nil))
+	}
+
+	waitForState(t, queue, ids[0], transferRunning)
+	waitForState(t, queue, ids[1], transferRunning)
+	if info, _ := queue.Job(ids[2]); info.State != transferQueued {
+		t.Errorf("expected the third job to wait, got %s", info.State)
+	}
+
+	// A paused job frees its slot for the next one
+	queue.Pause(ids[0])
+	waitForState(t, queue, ids[2], transferRunning)
+
+	queue.SetConcurrency(3)
+	queue.Resume(ids[0])
+	waitForState(t, queue, ids[0], transferRunning)
+
+	close(release)
+	for _, id := range ids {
+		waitForState(t, queue, id, transferDone)
+	}
+}
+
+func TestTransferBatch(t *testing.T) {
+	batch := newTransferBatch(3)
+
+	steps := []struct {
+		job       int
+		state     transferState
+		done      bool
+		failed    int
+		cancelled int
+	}{
+		{0, transferDone, false, 0, 0},
+		{1, transferFailed, false, 1, 0},
+		{2, transferCancelled, true, 1, 1},
+		// Retries finish again without counting the job twice
+		{1, transferDone, true, 0, 1},
+		{2, transferFailed, true, 1, 0},
+		{2, transferDone, true, 0, 0},
+	}
+	for i, step := range steps {
+		done, failed, cancelled := batch.finish(step.job, step.state)
+		if done != step.done || failed != step.failed || cancelled != step.cancelled {
+			t.Errorf("step %d: expected done %v with %d failed and %d cancelled, got done %v with %d failed and %d cancelled",
+				i, step.done, step.failed, step.cancelled, done, failed, cancelled)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// rangeServingClient returns a mock client serving byte ranges of content and
+// recording the ranges requested
+func rangeServingClient(content []byte, requested *[]string, mu *sync.Mutex) *mockS3Client {
+	return &mockS3Client{
+		GetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			var start, end int64
+			if _, err := fmt.Sscanf(*params.Range, "bytes=%d-%d", &start, &end); err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			*requested = append(*requested, *params.Range)
+			mu.Unlock()
+			return &s3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(content[start : end+1])),
+			}, nil
+		},
+		HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(content)))}, nil
+		},
+	}
+}
+
+func withSmallChunks(t *testing.T) {
+	oldChunkSize := rangedChunkSize
+	rangedChunkSize = 4
+	t.Cleanup(func() {
+		rangedChunkSize = oldChunkSize
+	})
+}
+
+func quotedMD5(content []byte) string {
+	sum := md5.Sum(content)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func TestDownloadRanged(t *testing.T) {
+	withSmallChunks(t)
+	content := []byte("0123456789abcdefghij-")
+
+	var requested []string
+	var mu sync.Mutex
+	mockClient := rangeServingClient(content, &requested, &mu)
+
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+	var lastProgress int64
+	err := downloadRanged(context.TODO(), mockClient, "test-bucket", "big.bin", localPath, int64(len(content)), quotedMD5(content), false, func(current, total int64) {
+		mu.Lock()
+		lastProgress = current
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("downloadRanged returned an error: %v", err)
+	}
+
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatalf("failed to read download: %v", err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("expected content %q, got %q", content, data)
+	}
+	if len(requested) != 6 {
+		t.Errorf("expected 6 range requests, got %d", len(requested))
+	}
+	if lastProgress != int64(len(content)) {
+		t.Errorf("expected progress to reach %d, got %d", len(content), lastProgress)
+	}
+	if _, err := os.Stat(localPath + manifestSuffix); !os.IsNotExist(err) {
+		t.Error("expected the manifest to be removed after a successful download")
+	}
+}
+
+func TestDownloadRangedResumes(t *testing.T) {
+	withSmallChunks(t)
+	content := []byte("0123456789abcdef")
+	etag := quotedMD5(content)
+
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+
+	// The first two chunks were written before the previous attempt stopped
+	partial := make([]byte, len(content))
+	copy(partial, content[:8])
+	if err := os.WriteFile(localPath+partialSuffix, partial, 0644); err != nil {
+		t.Fatalf("failed to write partial file: %v", err)
+	}
+	manifest := &downloadManifest{
+		Bucket:    "test-bucket",
+		Key:       "big.bin",
+		ETag:      etag,
+		Size:      int64(len(content)),
+		ChunkSize: 4,
+		Completed: []bool{true, true, false, false},
+	}
+	if err := manifest.save(localPath + manifestSuffix); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	var requested []string
+	var mu sync.Mutex
+	mockClient := rangeServingClient(content, &requested, &mu)
+
+	if err := downloadRanged(context.TODO(), mockClient, "test-bucket", "big.bin", localPath, int64(len(content)), etag, false, nil); err != nil {
+		t.Fatalf("downloadRanged returned an error: %v", err)
+	}
+
+	if len(requested) != 2 {
+		t.Errorf("expected only the 2 missing ranges to be requested, got %v", requested)
+	}
+	data, _ := os.ReadFile(localPath)
+	if !bytes.Equal(data, content) {
+		t.Errorf("expected content %q, got %q", content, data)
+	}
+}
+
+func TestDownloadRangedVerifiesChecksum(t *testing.T) {
+	withSmallChunks(t)
+	content := []byte("0123456789")
+
+	var requested []string
+	var mu sync.Mutex
+	mockClient := rangeServingClient(content, &requested, &mu)
+
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+	err := downloadRanged(context.TODO(), mockClient, "test-bucket", "big.bin", localPath, int64(len(content)), `"00000000000000000000000000000000"`, true, nil)
+	if err == nil {
+		t.Fatal("expected a checksum mismatch error")
+	}
+
+	for _, path := range []string{localPath, localPath + partialSuffix, localPath + manifestSuffix} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed after a checksum mismatch", path)
+		}
+	}
+}
+
+func TestMultipartETagMD5(t *testing.T) {
+	content := []byte("aaaabbbbcc")
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	var digests []byte
+	for _, part := range [][]byte{content[:4], content[4:8], content[8:]} {
+		sum := md5.Sum(part)
+		digests = append(digests, sum[:]...)
+	}
+	sum := md5.Sum(digests)
+	expected := hex.EncodeToString(sum[:]) + "-3"
+
+	actual, err := multipartETagMD5(path, 4)
+	if err != nil {
+		t.Fatalf("multipartETagMD5 returned an error: %v", err)
+	}
+	if actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+
+	mockClient := &mockS3Client{
+		HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			if params.PartNumber == nil || *params.PartNumber != 1 {
+				t.Errorf("expected a HeadObject request for part 1")
+			}
+			return &s3.HeadObjectOutput{ContentLength: aws.Int64(4)}, nil
+		},
+	}
+	if err := verifyDownload(context.TODO(), mockClient, "test-bucket", "file", path, `"`+expected+`"`); err != nil {
+		t.Errorf("expected multipart ETag to verify, got %v", err)
+	}
+}
+
+func TestVerifyDownloadSkipsNonMD5ETags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("aaaabbbbcc"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	wrong := `"00000000000000000000000000000000"`
+
+	for _, test := range []struct {
+		name string
+		etag string
+		head s3.HeadObjectOutput
+	}{
+		{"SSE-KMS", wrong, s3.HeadObjectOutput{ServerSideEncryption: types.ServerSideEncryptionAwsKms}},
+		{"SSE-C", wrong, s3.HeadObjectOutput{SSECustomerAlgorithm: aws.String("AES256")}},
+		// 10 bytes in parts of 4 would be 3 parts, so the parts were uneven
+		{"uneven parts", `"00000000000000000000000000000000-2"`, s3.HeadObjectOutput{ContentLength: aws.Int64(4), PartsCount: aws.Int32(2)}},
+	} {
+		mockClient := &mockS3Client{
+			HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+				return &test.head, nil
+			},
+		}
+		if err := verifyDownload(context.TODO(), mockClient, "test-bucket", "file", path, test.etag); err != nil {
+			t.Errorf("%s: expected the check to be skipped, got %v", test.name, err)
+		}
+	}
+
+	mockClient := &mockS3Client{
+		HeadObjectFunc: func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{ServerSideEncryption: types.ServerSideEncryptionAes256}, nil
+		},
+	}
+	if err := verifyDownload(context.TODO(), mockClient, "test-bucket", "file", path, wrong); !errors.Is(err, errChecksumMismatch) {
+		t.Errorf("expected a checksum mismatch for an SSE-S3 object, got %v", err)
+	}
+}
+
+func TestDownloadRangedKeepsUnverifiedDownload(t *testing.T) {
+	withSmallChunks(t)
+	content := []byte("0123456789")
+
+	var requested []string
+	var mu sync.Mutex
+	mockClient := rangeServingClient(content, &requested, &mu)
+	mockClient.HeadObjectFunc = func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+		return nil, errors.New("access denied")
+	}
+
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+	err := downloadRanged(context.TODO(), mockClient, "test-bucket", "big.bin", localPath, int64(len(content)), quotedMD5(content), false, nil)
+	if err == nil {
+		t.Fatal("expected an error when the download cannot be verified")
+	}
+
+	for _, path := range []string{localPath + partialSuffix, localPath + manifestSuffix} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be kept when the download could not be verified: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// transfersRefreshInterval is how often the transfers panel and status line
+// are redrawn while transfers are active
+const transfersRefreshInterval = 250 * time.Millisecond
+
+// formatRate formats a transfer rate in bytes per second
+func formatRate(rate float64) string {
+	if rate <= 0 {
+		return ""
+	}
+	return formatBytes(int64(rate)) + "/s"
+}
+
+// formatDuration formats a duration as h:mm:ss or m:ss
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d / time.Hour)
+	m := int(d/time.Minute) % 60
+	s := int(d/time.Second) % 60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// formatTransferProgress formats the bytes done of a transfer
+func formatTransferProgress(info transferInfo) string {
+	if info.Total > 0 {
+		return fmt.Sprintf("%3.0f%% %s / %s", float64(info.Current)*100/float64(info.Total), formatBytes(info.Current), formatBytes(info.Total))
+	}
+	if info.Current > 0 {
+		return formatBytes(info.Current)
+	}
+	return ""
+}
+
+// transferStateColor returns the color a job state is shown in
+func transferStateColor(state transferState) tcell.Color {
+	switch state {
+	case transferRunning:
+		return tcell.ColorGreen
+	case transferPausedState:
+		return tcell.ColorYellow
+	case transferFailed:
+		return tcell.ColorRed
+	case transferDone, transferCancelled:
+		return tcell.ColorGray
+	}
+	return tview.Styles.PrimaryTextColor
+}
+
+// summarizeTransfers describes the jobs for the status line, or returns an
+// empty string if there is nothing worth showing
+func summarizeTransfers(infos []transferInfo) string {
+	counts := make(map[string]int)
+	var rate float64
+	for _, info := range infos {
+		counts[info.StateName()]++
+		rate += info.Rate
+	}
+
+	summary := ""
+	for _, state := range []string{"Running", "Queued", "Paused", "Stopped", "Failed"} {
+		if counts[state] == 0 {
+			continue
+		}
+		if summary != "" {
+			summary += ", "
+		}
+		summary += fmt.Sprintf("%d %s", counts[state], state)
+	}
+	if summary == "" {
+		return ""
+	}
+	if rate > 0 {
+		summary += " at " + formatRate(rate)
+	}
+	return "Transfers: " + summary
+}
+
+// newTransfersPanel creates the table listing the jobs of queue, and a
+// function that redraws it from a snapshot of the jobs. Keys: p pause or
+// resume, r retry, x/Delete cancel, c clear finished, +/- change the number
+// of concurrent transfers, Enter show details, ESC return to browsing.
+func newTransfersPanel(queue *TransferQueue, onConcurrencyChange func(concurrency int), onDetail func(id int), onClose func()) (*tview.Table, func(infos []transferInfo)) {
+	table := tview.NewTable().
+		SetBorders(false).
+		SetSelectable(true, false).
+		SetFixed(1, 0)
+	table.SetBorder(true)
+
+	var jobIDs []int
+
+	selectedJob := func() (int, bool) {
+		row, _ := table.GetSelection()
+		if row > 0 && row-1 < len(jobIDs) { // Skip header row
+			return jobIDs[row-1], true
+		}
+		return 0, false
+	}
+
+	refresh := func(infos []transferInfo) {
+		table.SetTitle(fmt.Sprintf(" Transfers (%d at a time) - p: pause/resume (stop/restart), r: retry, x: cancel, c: clear finished, +/-: concurrency, Enter: details, ESC: back ", queue.Concurrency()))
+
+		headers := []string{"#", "State", "Transfer", "Progress", "Rate", "ETA", "Info"}
+		for column, header := range headers {
+			table.SetCell(0, column, tview.NewTableCell(header).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		}
+
+		jobIDs = jobIDs[:0]
+		for i, info := range infos {
+			row := i + 1
+			jobIDs = append(jobIDs, info.ID)
+
+			eta := ""
+			if info.ETA > 0 {
+				eta = formatDuration(info.ETA)
+			}
+			message := info.Detail
+			if info.Err != nil {
+				message = info.Err.Error()
+			}
+
+			color := transferStateColor(info.State)
+			table.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("%d", info.ID)))
+			table.SetCell(row, 1, tview.NewTableCell(info.StateName()).SetTextColor(color))
+			table.SetCell(row, 2, tview.NewTableCell(info.Action+" "+info.Name).SetMaxWidth(50))
+			table.SetCell(row, 3, tview.NewTableCell(formatTransferProgress(info)))
+			table.SetCell(row, 4, tview.NewTableCell(formatRate(info.Rate)))
+			table.SetCell(row, 5, tview.NewTableCell(eta))
+			table.SetCell(row, 6, tview.NewTableCell(message).SetTextColor(color).SetExpansion(1))
+		}
+
+		// Drop rows of jobs that were cleared
+		for table.GetRowCount() > len(infos)+1 {
+			table.RemoveRow(table.GetRowCount() - 1)
+		}
+		if row, _ := table.GetSelection(); row > len(infos) && len(infos) > 0 {
+			table.Select(len(infos), 0)
+		}
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			onClose()
+			return nil
+		}
+		if event.Key() == tcell.KeyEnter {
+			if id, ok := selectedJob(); ok {
+				onDetail(id)
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyDelete {
+			if id, ok := selectedJob(); ok {
+				queue.Cancel(id)
+			}
+			return nil
+		}
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+
+		switch event.Rune() {
+		case 'p':
+			if id, ok := selectedJob(); ok {
+				if info, _ := queue.Job(id); info.State == transferPausedState {
+					queue.Resume(id)
+				} else {
+					queue.Pause(id)
+				}
+			}
+		case 'r':
+			if id, ok := selectedJob(); ok {
+				queue.Retry(id)
+			}
+		case 'x':
+			if id, ok := selectedJob(); ok {
+				queue.Cancel(id)
+			}
+		case 'c':
+			queue.ClearFinished()
+		case '+':
+			queue.SetConcurrency(queue.Concurrency() + 1)
+			onConcurrencyChange(queue.Concurrency())
+		case '-':
+			queue.SetConcurrency(queue.Concurrency() - 1)
+			onConcurrencyChange(queue.Concurrency())
+		default:
+			return event
+		}
+		refresh(queue.Jobs())
+		return nil
+	})
+
+	return table, refresh
+}
+
+// showTransferDetail displays the progress of a single job, and returns a
+// function that redraws it from the job's latest snapshot. onClose is called
+// when the window is dismissed; the job keeps running in the background.
+func showTransferDetail(queue *TransferQueue, id int, onClose func()) (*tview.Modal, func()) {
+	// A job that cannot resume is stopped and restarted instead
+	pauseLabel := "Stop/Restart"
+	if info, _ := queue.Job(id); info.Resumable {
+		pauseLabel = "Pause/Resume"
+	}
+	modal := tview.NewModal().
+		AddButtons([]string{pauseLabel, "Cancel", "Hide"})
+
+	refresh := func() {
+		info, ok := queue.Job(id)
+		if !ok {
+			return
+		}
+
+		text := formatProgressText(info.Action, info.Name, info.Detail, info.Current, info.Total)
+		text += fmt.Sprintf("\n%s\n\n%s", formatTransferStats(info.Elapsed, info.Rate, info.ETA), info.StateName())
+		if info.Err != nil {
+			text += fmt.Sprintf("\n\nError: %v", info.Err)
+		}
+		modal.SetText(text)
+	}
+	refresh()
+
+	modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		switch buttonLabel {
+		case pauseLabel:
+			if info, _ := queue.Job(id); info.State == transferPausedState {
+				queue.Resume(id)
+			} else {
+				queue.Pause(id)
+			}
+			refresh()
+		case "Cancel":
+			queue.Cancel(id)
+			refresh()
+		default:
+			onClose()
+		}
+	})
+
+	return modal, refresh
+}
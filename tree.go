@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxTreeFiles limits the objects shown under a folder of the tree view; the
+// others are only counted
+const maxTreeFiles = 100
+
+// treeLevel holds the folders and objects directly under a prefix, as shown
+// when the prefix is expanded in the tree view
+type treeLevel struct {
+	folders []string
+	files   []ObjectEntry // the first maxTreeFiles objects
+	count   int           // all objects
+	size    int64         // total size of all objects
+}
+
+// add adds the entries of one listing page
+func (l *treeLevel) add(entries []ObjectEntry) {
+	for _, entry := range entries {
+		if entry.IsDirectory {
+			l.folders = append(l.folders, entry.Key)
+			continue
+		}
+		l.count++
+		l.size += entry.Size
+		if len(l.files) < maxTreeFiles {
+			l.files = append(l.files, entry)
+		}
+	}
+}
+
+// summary describes the level beside its node
+func (l treeLevel) summary() string {
+	return fmt.Sprintf("%d folder(s), %d object(s), %s", len(l.folders), l.count, formatFileSize(l.size))
+}
+
+// listTreeLevel lists the folders and objects directly under prefix,
+// following every page of a delimited listing
+func listTreeLevel(ctx context.Context, client S3Client, bucketName, prefix string) (treeLevel, error) {
+	var level treeLevel
+	seen := make(map[string]bool)
+	var continuationToken *string
+	for {
+		page, err := listS3ObjectsPage(ctx, client, bucketName, prefix, continuationToken)
+		if err != nil {
+			return level, err
+		}
+		level.add(listingEntries(page, prefix, seen))
+		if page.IsTruncated == nil || !*page.IsTruncated {
+			return level, nil
+		}
+		continuationToken = page.NextContinuationToken
+	}
+}
+
+// prefixPath returns the prefixes leading down to prefix, outermost first:
+// "a/b/" gives "a/" and "a/b/"
+func prefixPath(prefix string) []string {
+	var path []string
+	for i, r := range prefix {
+		if r == '/' {
+			path = append(path, prefix[:i+1])
+		}
+	}
+	return path
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestListTreeLevel(t *testing.T) {
+	// Two pages: the first has the marker of data/ itself, a folder and more
+	// objects than are shown, the second another folder
+	first := []types.Object{{Key: aws.String("data/"), Size: aws.Int64(0)}}
+	for i := 0; i < maxTreeFiles+5; i++ {
+		first = append(first, types.Object{Key: aws.String(fmt.Sprintf("data/file-%d.txt", i)), Size: aws.Int64(10)})
+	}
+
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if params.Delimiter == nil || *params.Delimiter != "/" {
+				t.Errorf("expected a delimited listing")
+			}
+			if *params.Prefix != "data/" {
+				t.Errorf("expected prefix 'data/', got '%s'", *params.Prefix)
+			}
+			if params.ContinuationToken == nil {
+				return &s3.ListObjectsV2Output{
+					CommonPrefixes:        []types.CommonPrefix{{Prefix: aws.String("data/a/")}},
+					Contents:              first,
+					IsTruncated:           aws.Bool(true),
+					NextContinuationToken: aws.String("next"),
+				}, nil
+			}
+			return &s3.ListObjectsV2Output{
+				CommonPrefixes: []types.CommonPrefix{{Prefix: aws.String("data/b/")}},
+			}, nil
+		},
+	}
+
+	level, err := listTreeLevel(context.TODO(), mockClient, "test-bucket", "data/")
+	if err != nil {
+		t.Fatalf("listTreeLevel returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(level.folders, []string{"data/a/", "data/b/"}) {
+		t.Errorf("expected folders data/a/ and data/b/, got %v", level.folders)
+	}
+	if len(level.files) != maxTreeFiles {
+		t.Errorf("expected %d objects shown, got %d", maxTreeFiles, len(level.files))
+	}
+	if level.count != maxTreeFiles+5 {
+		t.Errorf("expected %d objects counted, got %d", maxTreeFiles+5, level.count)
+	}
+	if level.size != int64(10*(maxTreeFiles+5)) {
+		t.Errorf("expected a total size of %d, got %d", 10*(maxTreeFiles+5), level.size)
+	}
+
+	want := fmt.Sprintf("2 folder(s), %d object(s), %s", maxTreeFiles+5, formatFileSize(level.size))
+	if got := level.summary(); got != want {
+		t.Errorf("expected summary %q, got %q", want, got)
+	}
+}
+
+func TestPrefixPath(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", nil},
+		{"a/", []string{"a/"}},
+		{"a/b/c/", []string{"a/", "a/b/", "a/b/c/"}},
+	}
+	for _, tt := range tests {
+		if got := prefixPath(tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("prefixPath(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// treeNode is the reference of a node in the tree view: a folder, with
+// whether its level has been listed, or an object
+type treeNode struct {
+	key     string
+	isDir   bool
+	loaded  bool
+	loading bool
+}
+
+// bucketTree shows a bucket as a collapsible tree. Each folder is listed
+// when it is first expanded, one level at a time.
+type bucketTree struct {
+	flex   *tview.Flex
+	header *tview.TextView
+	view   *tview.TreeView
+	bucket string
+
+	// load lists the level under prefix and calls done with it on the UI
+	// goroutine
+	load func(prefix string, done func(level treeLevel, err error))
+
+	// pending are the folders still to expand on the way to the prefix the
+	// tree was opened at
+	pending []string
+}
+
+// newBucketTree creates the tree of bucketName and expands it down to
+// prefix. onView is called with an object's key on Enter, onOpen with the
+// selected node's key to show it in the listing ('o'), and onClose when the
+// tree is left with ESC.
+func newBucketTree(bucketName, prefix string, load func(prefix string, done func(level treeLevel, err error)), onView func(key string), onOpen func(key string, isDir bool), onClose func()) *bucketTree {
+	t := &bucketTree{
+		header:  tview.NewTextView().SetTextAlign(tview.AlignCenter),
+		view:    tview.NewTreeView(),
+		bucket:  bucketName,
+		load:    load,
+		pending: prefixPath(prefix),
+	}
+	t.view.SetBorder(true).SetTitle(fmt.Sprintf(" Tree of s3://%s (Enter/→: expand, ←: collapse, Enter: view file, 'o': open in listing, ESC: back) ", bucketName))
+	t.flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(t.header, 3, 1, false).
+		AddItem(t.view, 0, 1, true)
+
+	root := tview.NewTreeNode("").SetReference(&treeNode{isDir: true}).SetColor(tcell.ColorBlue)
+	t.view.SetRoot(root)
+	t.view.SetChangedFunc(func(node *tview.TreeNode) {
+		t.header.SetText(fmt.Sprintf("s3://%s/%s", t.bucket, node.GetReference().(*treeNode).key))
+	})
+
+	t.view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		node := t.view.GetCurrentNode()
+		if node == nil {
+			return event
+		}
+		ref := node.GetReference().(*treeNode)
+		switch {
+		case event.Key() == tcell.KeyEscape:
+			onClose()
+		case event.Key() == tcell.KeyEnter:
+			switch {
+			case !ref.isDir:
+				onView(ref.key)
+			case node.IsExpanded():
+				node.Collapse()
+			default:
+				t.expand(node)
+			}
+		case event.Key() == tcell.KeyRight:
+			if ref.isDir && !node.IsExpanded() {
+				t.expand(node)
+				return nil
+			}
+			return event
+		case event.Key() == tcell.KeyLeft:
+			if ref.isDir && node.IsExpanded() {
+				node.Collapse()
+			} else if path := t.view.GetPath(node); len(path) > 1 {
+				t.selectNode(path[len(path)-2])
+			}
+		case event.Key() == tcell.KeyRune && event.Rune() == 'o':
+			onOpen(ref.key, ref.isDir)
+		default:
+			return event
+		}
+		return nil
+	})
+
+	t.setText(root, nil)
+	t.selectNode(root)
+	t.expand(root)
+	return t
+}
+
+// selectNode moves the selection to node
+func (t *bucketTree) selectNode(node *tview.TreeNode) {
+	t.view.SetCurrentNode(node)
+	t.header.SetText(fmt.Sprintf("s3://%s/%s", t.bucket, node.GetReference().(*treeNode).key))
+}
+
+// setText labels node with its name and, once it has been listed, the
+// summary of its level
+func (t *bucketTree) setText(node *tview.TreeNode, level *treeLevel) {
+	ref := node.GetReference().(*treeNode)
+	name := t.bucket
+	if ref.key != "" {
+		name = entryBaseName(ref.key)
+	}
+	if ref.isDir {
+		name += "/"
+	}
+	text := tview.Escape(name)
+	switch {
+	case ref.loading:
+		text += " [gray](loading…)[-]"
+	case level != nil:
+		text += " [gray](" + level.summary() + ")[-]"
+	}
+	node.SetText(text)
+}
+
+// expand shows the children of a folder node, listing them first if that
+// has not been done yet
+func (t *bucketTree) expand(node *tview.TreeNode) {
+	node.Expand()
+	ref := node.GetReference().(*treeNode)
+	if ref.loaded || ref.loading {
+		return
+	}
+
+	ref.loading = true
+	t.setText(node, nil)
+	t.load(ref.key, func(level treeLevel, err error) {
+		ref.loading = false
+		if err != nil {
+			// Expanding the folder again retries
+			node.Collapse()
+			t.setText(node, nil)
+			node.SetText(node.GetText() + fmt.Sprintf(" [red](%s)[-]", tview.Escape(err.Error())))
+			return
+		}
+		ref.loaded = true
+		t.fill(node, level)
+	})
+}
+
+// fill adds the listed level as the children of node, and continues
+// expanding towards the prefix the tree was opened at
+func (t *bucketTree) fill(node *tview.TreeNode, level treeLevel) {
+	key := node.GetReference().(*treeNode).key
+	node.ClearChildren()
+	t.setText(node, &level)
+
+	var next *tview.TreeNode
+	for _, folder := range level.folders {
+		child := tview.NewTreeNode("").SetReference(&treeNode{key: folder, isDir: true}).SetColor(tcell.ColorBlue)
+		t.setText(child, nil)
+		node.AddChild(child)
+		if len(t.pending) > 0 && folder == t.pending[0] {
+			next = child
+		}
+	}
+	for _, file := range level.files {
+		child := tview.NewTreeNode(fmt.Sprintf("%s [gray](%s)[-]", tview.Escape(entryBaseName(file.Key)), formatFileSize(file.Size))).
+			SetReference(&treeNode{key: file.Key})
+		node.AddChild(child)
+	}
+	if hidden := level.count - len(level.files); hidden > 0 {
+		node.AddChild(tview.NewTreeNode(fmt.Sprintf("… %d more object(s)", hidden)).
+			SetReference(&treeNode{key: key}).
+			SetSelectable(false).
+			SetColor(tcell.ColorGray))
+	}
+
+	// Folders expanded by hand meanwhile do not lead there
+	if len(t.pending) == 0 || parentPrefix(t.pending[0]) != key {
+		return
+	}
+	if next == nil {
+		t.pending = nil
+		return
+	}
+	t.pending = t.pending[1:]
+	t.selectNode(next)
+	t.expand(next)
+}
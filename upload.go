@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Files at or above multipartThreshold are uploaded in parts of
+// multipartPartSize bytes. S3 requires every part except the last to be at
+// least 5 MiB and allows at most 10000 parts per upload.
+var (
+	multipartThreshold int64 = 64 * 1024 * 1024
+	multipartPartSize  int64 = 16 * 1024 * 1024
+)
+
+const maxUploadParts = 10000
+
+// progressReadSeeker wraps an io.ReadSeeker and reports how many bytes were
+// read. Seeking (e.g. when the SDK rewinds a body to retry a request) reports
+// a negative delta so the accumulated progress stays accurate.
+type progressReadSeeker struct {
+	rs        io.ReadSeeker
+	pos       int64
+	onAdvance func(delta int64)
+}
+
+func (p *progressReadSeeker) Read(b []byte) (int, error) {
+	n, err := p.rs.Read(b)
+	p.pos += int64(n)
+	if n > 0 && p.onAdvance != nil {
+		p.onAdvance(int64(n))
+	}
+	return n, err
+}
+
+func (p *progressReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	newPos, err := p.rs.Seek(offset, whence)
+	if err != nil {
+		return newPos, err
+	}
+	if delta := newPos - p.pos; delta != 0 && p.onAdvance != nil {
+		p.onAdvance(delta)
+	}
+	p.pos = newPos
+	return newPos, nil
+}
+
+// contentTypeFor guesses the content type of a file from its extension
+func contentTypeFor(path string) *string {
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return aws.String(contentType)
+	}
+	return nil
+}
+
+// uploadFile uploads a single local file to bucketName/key. Files larger than
+// multipartThreshold are sent as a multipart upload, which is aborted if any
+// part fails or ctx is cancelled. onAdvance receives the number of bytes sent
+// since the previous call.
+func uploadFile(ctx context.Context, client S3Client, bucketName, key, localPath string, onAdvance func(delta int64)) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
+	}
+
+	size := info.Size()
+	if size < multipartThreshold {
+		_, err = client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        &bucketName,
+			Key:           &key,
+			Body:          &progressReadSeeker{rs: file, onAdvance: onAdvance},
+			ContentLength: aws.Int64(size),
+			ContentType:   contentTypeFor(localPath),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		return nil
+	}
+
+	return uploadFileMultipart(ctx, client, bucketName, key, file, size, onAdvance)
+}
+
+// uploadFileMultipart uploads file in sequential parts
+func uploadFileMultipart(ctx context.Context, client S3Client, bucketName, key string, file *os.File, size int64, onAdvance func(delta int64)) error {
+	// Grow the part size if the file would otherwise need too many parts
+	partSize := multipartPartSize
+	if size/partSize >= maxUploadParts {
+		partSize = size/(maxUploadParts-1) + 1
+	}
+
+	created, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      &bucketName,
+		Key:         &key,
+		ContentType: contentTypeFor(file.Name()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload for %s: %w", key, err)
+	}
+
+	abort := func(cause error) error {
+		// Use a fresh context so the abort still goes out after cancellation
+		client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   &bucketName,
+			Key:      &key,
+			UploadId: created.UploadId,
+		})
+		return cause
+	}
+
+	var parts []types.CompletedPart
+	for offset, partNumber := int64(0), int32(1); offset < size; offset, partNumber = offset+partSize, partNumber+1 {
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+
+		result, err := client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        &bucketName,
+			Key:           &key,
+			UploadId:      created.UploadId,
+			PartNumber:    aws.Int32(partNumber),
+			Body:          &progressReadSeeker{rs: io.NewSectionReader(file, offset, length), onAdvance: onAdvance},
+			ContentLength: aws.Int64(length),
+		})
+		if err != nil {
+			return abort(fmt.Errorf("failed to upload part %d of %s: %w", partNumber, key, err))
+		}
+
+		parts = append(parts, types.CompletedPart{
+			ETag:       result.ETag,
+			PartNumber: aws.Int32(partNumber),
+		})
+	}
+
+	_, err = client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &bucketName,
+		Key:             &key,
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("failed to complete multipart upload for %s: %w", key, err))
+	}
+
+	return nil
+}
+
+// uploadTarget is a local file and the key it will be uploaded to
+type uploadTarget struct {
+	localPath string
+	key       string
+	size      int64
+}
+
+// collectUploadTargets resolves localPath (a file or a directory tree) into
+// the list of files to upload. Keys are built from prefix plus the path
+// relative to the parent of localPath, so uploading "photos" into "backup/"
+// produces "backup/photos/...".
+func collectUploadTargets(localPath, prefix string) ([]uploadTarget, error) {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []uploadTarget{{
+			localPath: localPath,
+			key:       prefix + filepath.Base(localPath),
+			size:      info.Size(),
+		}}, nil
+	}
+
+	base := filepath.Dir(filepath.Clean(localPath))
+	var targets []uploadTarget
+	err = filepath.WalkDir(localPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fileInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, uploadTarget{
+			localPath: path,
+			key:       prefix + filepath.ToSlash(rel),
+			size:      fileInfo.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+// uploadPath uploads a local file or directory tree into bucketName/prefix,
+// reporting the aggregate progress over all files. It returns the number of
+// files uploaded.
+func uploadPath(ctx context.Context, client S3Client, bucketName, prefix, localPath string, onProgress func(current, total int64)) (int, error) {
+	targets, err := collectUploadTargets(localPath, prefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", localPath, err)
+	}
+
+	var total int64
+	for _, target := range targets {
+		total += target.size
+	}
+
+	var sent int64
+	onAdvance := func(delta int64) {
+		current := atomic.AddInt64(&sent, delta)
+		if onProgress != nil {
+			onProgress(current, total)
+		}
+	}
+
+	for i, target := range targets {
+		if err := uploadFile(ctx, client, bucketName, target.key, target.localPath, onAdvance); err != nil {
+			return i, err
+		}
+	}
+
+	return len(targets), nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func writeTestFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+}
+
+func TestCollectUploadTargets(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "photos", "a.jpg"), 3)
+	writeTestFile(t, filepath.Join(dir, "photos", "2024", "b.jpg"), 5)
+
+	targets, err := collectUploadTargets(filepath.Join(dir, "photos"), "backup/")
+	if err != nil {
+		t.Fatalf("collectUploadTargets returned an error: %v", err)
+	}
+
+	expected := map[string]int64{
+		"backup/photos/a.jpg":      3,
+		"backup/photos/2024/b.jpg": 5,
+	}
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %d targets, got %d", len(expected), len(targets))
+	}
+	for _, target := range targets {
+		size, ok := expected[target.key]
+		if !ok {
+			t.Errorf("unexpected key '%s'", target.key)
+			continue
+		}
+		if target.size != size {
+			t.Errorf("expected size %d for '%s', got %d", size, target.key, target.size)
+		}
+	}
+
+	single, err := collectUploadTargets(filepath.Join(dir, "photos", "a.jpg"), "")
+	if err != nil {
+		t.Fatalf("collectUploadTargets returned an error: %v", err)
+	}
+	if len(single) != 1 || single[0].key != "a.jpg" {
+		t.Errorf("expected a single target 'a.jpg', got %+v", single)
+	}
+}
+
+func TestUploadFileSinglePut(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	writeTestFile(t, path, 10)
+
+	var gotKey string
+	var gotBody []byte
+	mockClient := &mockS3Client{
+		PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			gotKey = *params.Key
+			gotBody, _ = io.ReadAll(params.Body)
+			return &s3.PutObjectOutput{}, nil
+		},
+	}
+
+	var progress int64
+	err := uploadFile(context.TODO(), mockClient, "test-bucket", "docs/notes.txt", path, func(delta int64) {
+		progress += delta
+	})
+	if err != nil {
+		t.Fatalf("uploadFile returned an error: %v", err)
+	}
+
+	if gotKey != "docs/notes.txt" {
+		t.Errorf("expected key 'docs/notes.txt', got '%s'", gotKey)
+	}
+	if len(gotBody) != 10 {
+		t.Errorf("expected 10 bytes uploaded, got %d", len(gotBody))
+	}
+	if progress != 10 {
+		t.Errorf("expected progress of 10 bytes, got %d", progress)
+	}
+}
+
+func TestUploadFileMultipart(t *testing.T) {
+	oldThreshold, oldPartSize := multipartThreshold, multipartPartSize
+	multipartThreshold, multipartPartSize = 8, 4
+	defer func() {
+		multipartThreshold, multipartPartSize = oldThreshold, oldPartSize
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.bin")
+	writeTestFile(t, path, 10)
+
+	var partSizes []int
+	var completedParts int
+	mockClient := &mockS3Client{
+		CreateMultipartUploadFunc: func(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+		},
+		UploadPartFunc: func(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+			body, _ := io.ReadAll(params.Body)
+			partSizes = append(partSizes, len(body))
+			return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("etag-%d", *params.PartNumber))}, nil
+		},
+		CompleteMultipartUploadFunc: func(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+			completedParts = len(params.MultipartUpload.Parts)
+			return &s3.CompleteMultipartUploadOutput{}, nil
+		},
+	}
+
+	if err := uploadFile(context.TODO(), mockClient, "test-bucket", "big.bin", path, nil); err != nil {
+		t.Fatalf("uploadFile returned an error: %v", err)
+	}
+
+	if len(partSizes) != 3 || partSizes[0] != 4 || partSizes[1] != 4 || partSizes[2] != 2 {
+		t.Errorf("expected parts of 4, 4 and 2 bytes, got %v", partSizes)
+	}
+	if completedParts != 3 {
+		t.Errorf("expected 3 completed parts, got %d", completedParts)
+	}
+}
+
+func TestUploadFileMultipartAbortsOnError(t *testing.T) {
+	oldThreshold, oldPartSize := multipartThreshold, multipartPartSize
+	multipartThreshold, multipartPartSize = 8, 4
+	defer func() {
+		multipartThreshold, multipartPartSize = oldThreshold, oldPartSize
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.bin")
+	writeTestFile(t, path, 10)
+
+	aborted := false
+	mockClient := &mockS3Client{
+		CreateMultipartUploadFunc: func(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+			return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+		},
+		UploadPartFunc: func(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+			return nil, fmt.Errorf("connection reset")
+		},
+		AbortMultipartUploadFunc: func(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			aborted = *params.UploadId == "upload-1"
+			return &s3.AbortMultipartUploadOutput{}, nil
+		},
+	}
+
+	if err := uploadFile(context.TODO(), mockClient, "test-bucket", "big.bin", path, nil); err == nil {
+		t.Fatal("expected uploadFile to return an error")
+	}
+	if !aborted {
+		t.Error("expected the multipart upload to be aborted")
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// usageBarWidth is the width in cells of the bars in the usage screen
+const usageBarWidth = 24
+
+// maxUsageExtensions limits the extensions listed in the usage screen; the
+// others are added up in one group
+const maxUsageExtensions = 20
+
+// usageBarBlocks draw the partial cell at the end of a bar, in eighths
+var usageBarBlocks = []rune(" ▏▎▍▌▋▊▉")
+
+// usageGroup is one group of objects in the usage screen, with their usage
+type usageGroup struct {
+	name  string
+	usage dirUsage
+}
+
+// usageBreakdown adds up the objects under a prefix, by child folder, by
+// storage class and by file extension. Objects directly in the prefix are
+// grouped under the empty child name, objects without an extension under
+// the empty extension.
+type usageBreakdown struct {
+	prefix     string
+	total      dirUsage
+	children   map[string]*dirUsage
+	classes    map[string]*dirUsage
+	extensions map[string]*dirUsage
+}
+
+// newUsageBreakdown creates an empty breakdown of prefix
+func newUsageBreakdown(prefix string) *usageBreakdown {
+	return &usageBreakdown{
+		prefix:     prefix,
+		children:   make(map[string]*dirUsage),
+		classes:    make(map[string]*dirUsage),
+		extensions: make(map[string]*dirUsage),
+	}
+}
+
+// add counts object in the total and in each of its groups
+func (b *usageBreakdown) add(object types.Object) {
+	key := *object.Key
+	rest := strings.TrimPrefix(key, b.prefix)
+	child := ""
+	if i := strings.Index(rest, "/"); i >= 0 {
+		child = b.prefix + rest[:i+1]
+	}
+
+	class := string(object.StorageClass)
+	if class == "" {
+		class = string(types.ObjectStorageClassStandard)
+	}
+
+	b.total.add(object)
+	for _, group := range []struct {
+		groups map[string]*dirUsage
+		name   string
+	}{{b.children, child}, {b.classes, class}, {b.extensions, fileExtension(key)}} {
+		usage, ok := group.groups[group.name]
+		if !ok {
+			usage = &dirUsage{}
+			group.groups[group.name] = usage
+		}
+		usage.add(object)
+	}
+}
+
+// fileExtension returns the lower-cased extension of the object's name, or
+// "" if it has none. Folder markers and names starting with their only dot
+// have none.
+func fileExtension(key string) string {
+	if strings.HasSuffix(key, "/") {
+		return ""
+	}
+	name := entryBaseName(key)
+	ext := path.Ext(name)
+	if ext == name || ext == "." {
+		return ""
+	}
+	return strings.ToLower(ext)
+}
+
+// sortedGroups returns the groups largest first, then by name
+func sortedGroups(groups map[string]*dirUsage) []usageGroup {
+	sorted := make([]usageGroup, 0, len(groups))
+	for name, usage := range groups {
+		sorted = append(sorted, usageGroup{name: name, usage: *usage})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].usage.size != sorted[j].usage.size {
+			return sorted[i].usage.size > sorted[j].usage.size
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	return sorted
+}
+
+// limitGroups keeps the first max groups, adding up the rest in one group
+// called other
+func limitGroups(groups []usageGroup, max int, other string) []usageGroup {
+	if len(groups) <= max {
+		return groups
+	}
+	rest := usageGroup{name: other}
+	for _, group := range groups[max:] {
+		rest.usage.size += group.usage.size
+		rest.usage.count += group.usage.count
+		if group.usage.newest.After(rest.usage.newest) {
+			rest.usage.newest = group.usage.newest
+		}
+	}
+	return append(groups[:max:max], rest)
+}
+
+// usageBar draws size as a share of total in a bar width cells wide, with
+// eighths of a cell for precision
+func usageBar(size, total int64, width int) string {
+	if total <= 0 || size <= 0 {
+		return strings.Repeat(" ", width)
+	}
+	eighths := int(size * int64(width) * 8 / total)
+	if eighths == 0 {
+		eighths = 1 // Show that there is something
+	}
+	bar := strings.Repeat("█", eighths/8)
+	if eighths%8 > 0 {
+		bar += string(usageBarBlocks[eighths%8])
+	}
+	return bar + strings.Repeat(" ", width-len([]rune(bar)))
+}
+
+// formatShare formats size as a percentage of total
+func formatShare(size, total int64) string {
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(size)*100/float64(total))
+}
+
+// computeUsageBreakdown adds up every object under prefix. onProgress is
+// called with the total so far after each listing page's worth of objects.
+func computeUsageBreakdown(ctx context.Context, client S3Client, bucketName, prefix string, onProgress func(total dirUsage)) (*usageBreakdown, error) {
+	breakdown := newUsageBreakdown(prefix)
+	err := walkS3Objects(ctx, client, bucketName, prefix, func(object types.Object) error {
+		breakdown.add(object)
+		if breakdown.total.count%findBatchSize == 0 {
+			onProgress(breakdown.total)
+		}
+		return nil
+	})
+	return breakdown, err
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestUsageBreakdown(t *testing.T) {
+	breakdown := newUsageBreakdown("data/")
+	for _, object := range []types.Object{
+		{Key: aws.String("data/"), Size: aws.Int64(0)},
+		{Key: aws.String("data/readme.TXT"), Size: aws.Int64(10)},
+		{Key: aws.String("data/logs/a.log"), Size: aws.Int64(100), StorageClass: types.ObjectStorageClassGlacier},
+		{Key: aws.String("data/logs/2024/b.log"), Size: aws.Int64(200), StorageClass: types.ObjectStorageClassGlacier},
+		{Key: aws.String("data/img/c.png"), Size: aws.Int64(50)},
+	} {
+		breakdown.add(object)
+	}
+
+	if breakdown.total.count != 5 || breakdown.total.size != 360 {
+		t.Errorf("expected 5 objects of 360 bytes in total, got %d of %d", breakdown.total.count, breakdown.total.size)
+	}
+
+	children := sortedGroups(breakdown.children)
+	want := []struct {
+		name  string
+		size  int64
+		count int
+	}{{"data/logs/", 300, 2}, {"data/img/", 50, 1}, {"", 10, 2}}
+	if len(children) != len(want) {
+		t.Fatalf("expected %d children, got %d", len(want), len(children))
+	}
+	for i, w := range want {
+		if children[i].name != w.name || children[i].usage.size != w.size || children[i].usage.count != w.count {
+			t.Errorf("child %d: expected %s with %d bytes in %d objects, got %s with %d bytes in %d objects", i, w.name, w.size, w.count, children[i].name, children[i].usage.size, children[i].usage.count)
+		}
+	}
+
+	if usage := breakdown.classes["GLACIER"]; usage == nil || usage.size != 300 {
+		t.Errorf("expected 300 bytes in GLACIER, got %v", usage)
+	}
+	if usage := breakdown.classes["STANDARD"]; usage == nil || usage.size != 60 {
+		t.Errorf("expected objects without a class to count as STANDARD, got %v", usage)
+	}
+	if usage := breakdown.extensions[".log"]; usage == nil || usage.count != 2 {
+		t.Errorf("expected 2 .log objects, got %v", usage)
+	}
+	if usage := breakdown.extensions[".txt"]; usage == nil || usage.size != 10 {
+		t.Errorf("expected extensions to be lower-cased, got %v", usage)
+	}
+	if usage := breakdown.extensions[""]; usage == nil || usage.count != 1 {
+		t.Errorf("expected the folder marker without an extension, got %v", usage)
+	}
+}
+
+func TestFileExtension(t *testing.T) {
+	tests := map[string]string{
+		"a/b.tar.GZ": ".gz",
+		"a/b":        "",
+		"a/.hidden":  "",
+		"a/b.":       "",
+		"a/b.d/":     "",
+		"c.json":     ".json",
+	}
+	for key, want := range tests {
+		if got := fileExtension(key); got != want {
+			t.Errorf("fileExtension(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLimitGroups(t *testing.T) {
+	groups := []usageGroup{
+		{name: ".a", usage: dirUsage{size: 30, count: 3}},
+		{name: ".b", usage: dirUsage{size: 20, count: 2}},
+		{name: ".c", usage: dirUsage{size: 10, count: 1}},
+	}
+	limited := limitGroups(groups, 1, "(other)")
+	if len(limited) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(limited))
+	}
+	if limited[1].name != "(other)" || limited[1].usage.size != 30 || limited[1].usage.count != 3 {
+		t.Errorf("expected the rest added up, got %+v", limited[1])
+	}
+	if groups[1].name != ".b" {
+		t.Errorf("expected the groups passed in to be left alone, got %+v", groups[1])
+	}
+	if got := limitGroups(groups, 3, "(other)"); len(got) != 3 {
+		t.Errorf("expected no group added when under the limit, got %d groups", len(got))
+	}
+}
+
+func TestUsageBar(t *testing.T) {
+	tests := []struct {
+		size, total int64
+		want        string
+	}{
+		{0, 100, "    "},
+		{100, 100, "████"},
+		{50, 100, "██  "},
+		{1, 100, "▏   "},
+		{5, 16, "█▎  "},
+		{10, 0, "    "},
+	}
+	for _, tt := range tests {
+		if got := usageBar(tt.size, tt.total, 4); got != tt.want {
+			t.Errorf("usageBar(%d, %d, 4) = %q, want %q", tt.size, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestFormatShare(t *testing.T) {
+	if got := formatShare(1, 3); got != "33.3%" {
+		t.Errorf("expected 33.3%%, got %s", got)
+	}
+	if got := formatShare(1, 0); got != "-" {
+		t.Errorf("expected - for an empty total, got %s", got)
+	}
+}
+
+func TestComputeUsageBreakdown(t *testing.T) {
+	mockClient := &mockS3Client{
+		ListObjectsV2Func: func(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if params.Delimiter != nil {
+				t.Errorf("expected an undelimited listing, got delimiter '%s'", *params.Delimiter)
+			}
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("data/a/1.csv"), Size: aws.Int64(3)},
+					{Key: aws.String("data/b.csv"), Size: aws.Int64(4)},
+				},
+			}, nil
+		},
+	}
+
+	breakdown, err := computeUsageBreakdown(context.TODO(), mockClient, "test-bucket", "data/", func(dirUsage) {})
+	if err != nil {
+		t.Fatalf("computeUsageBreakdown returned an error: %v", err)
+	}
+	if breakdown.total.size != 7 || len(breakdown.children) != 2 || breakdown.extensions[".csv"].count != 2 {
+		t.Errorf("unexpected breakdown: total %+v, %d children", breakdown.total, len(breakdown.children))
+	}
+}
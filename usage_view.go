@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// usageView is the usage screen of a prefix: where the space under it goes,
+// by child folder, storage class and file extension
+type usageView struct {
+	flex     *tview.Flex
+	header   *tview.TextView
+	table    *tview.Table
+	bucket   string
+	prefix   string
+	children map[int]string // the child folder of each folder row
+	spinner  int
+}
+
+// newUsageView creates the usage screen of prefix, which shows progress
+// until show is called with the breakdown. onDrill is called with the
+// child folder to break down on Enter, onOpen with the folder to show in the
+// listing ('o'), and onClose when the screen is left with ESC.
+func newUsageView(bucketName, prefix string, onDrill func(child string), onOpen func(prefix string), onClose func()) *usageView {
+	v := &usageView{
+		header: tview.NewTextView().SetTextAlign(tview.AlignCenter),
+		table:  tview.NewTable().SetBorders(false).SetSelectable(true, false),
+		bucket: bucketName,
+		prefix: prefix,
+	}
+	v.table.SetBorder(true)
+	v.flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(v.header, 3, 1, false).
+		AddItem(v.table, 0, 1, true)
+	v.header.SetText(fmt.Sprintf("s3://%s/%s", bucketName, prefix))
+	v.progress(dirUsage{})
+
+	v.table.SetSelectionChangedFunc(func(row, column int) {
+		child, ok := v.children[row]
+		if !ok {
+			child = prefix
+		}
+		v.header.SetText(fmt.Sprintf("s3://%s/%s", bucketName, child))
+	})
+
+	v.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := v.table.GetSelection()
+		child, ok := v.children[row]
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyLeft:
+			onClose()
+		case event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyRight:
+			if ok {
+				onDrill(child)
+			}
+		case event.Key() == tcell.KeyRune && event.Rune() == 'o':
+			if !ok {
+				child = prefix
+			}
+			onOpen(child)
+		default:
+			return event
+		}
+		return nil
+	})
+
+	return v
+}
+
+// setTitle shows status with the key help
+func (v *usageView) setTitle(status string) {
+	v.table.SetTitle(fmt.Sprintf(" Usage of s3://%s/%s: %s (Enter: break down folder, 'o': open in listing, ESC: back) ", v.bucket, v.prefix, status))
+}
+
+// progress shows the total scanned so far
+func (v *usageView) progress(total dirUsage) {
+	v.spinner++
+	v.setTitle(fmt.Sprintf("%s scanning… %s", spinnerFrame(v.spinner), total))
+}
+
+// fail shows why the scan stopped
+func (v *usageView) fail(err error) {
+	v.setTitle(fmt.Sprintf("scan failed: %v", err))
+}
+
+// show renders the breakdown: the child folders, the storage classes and
+// the extensions, each largest first
+func (v *usageView) show(breakdown *usageBreakdown) {
+	total := breakdown.total
+	v.setTitle(total.String())
+	v.table.Clear()
+	v.children = make(map[int]string)
+
+	// section renders the groups below a header and returns their first row
+	row := 0
+	section := func(title string, groups []usageGroup, name func(group usageGroup) string) int {
+		if row > 0 {
+			v.table.SetCell(row, 0, tview.NewTableCell("").SetSelectable(false))
+			row++
+		}
+		for i, header := range []string{title, "Size", "Objects", "Share", ""} {
+			v.table.SetCell(row, i, tview.NewTableCell(header).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		}
+		row++
+		first := row
+		for _, group := range groups {
+			v.table.SetCell(row, 0, tview.NewTableCell(tview.Escape(name(group))))
+			v.table.SetCell(row, 1, tview.NewTableCell(formatFileSize(group.usage.size)).SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 2, tview.NewTableCell(fmt.Sprint(group.usage.count)).SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 3, tview.NewTableCell(formatShare(group.usage.size, total.size)).SetAlign(tview.AlignRight))
+			v.table.SetCell(row, 4, tview.NewTableCell(usageBar(group.usage.size, total.size, usageBarWidth)).SetTextColor(tcell.ColorGreen))
+			row++
+		}
+		return first
+	}
+
+	folders := sortedGroups(breakdown.children)
+	first := section("Folder", folders, func(group usageGroup) string {
+		if group.name == "" {
+			return "(objects in this folder)"
+		}
+		return entryBaseName(group.name) + "/"
+	})
+	for i, group := range folders {
+		if group.name != "" {
+			v.children[first+i] = group.name
+		}
+	}
+
+	section("Storage class", sortedGroups(breakdown.classes), func(group usageGroup) string {
+		return group.name
+	})
+
+	// Extensions start with a dot, so the group names cannot clash
+	extensions := limitGroups(sortedGroups(breakdown.extensions), maxUsageExtensions, "(other)")
+	section("Extension", extensions, func(group usageGroup) string {
+		if group.name == "" {
+			return "(none)"
+		}
+		return group.name
+	})
+
+	v.table.ScrollToBeginning()
+	v.table.Select(1, 0)
+}